@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	appsRefresh bool
+	appsAll     bool
+	appsDevice  string
+	appsClear   bool
+)
+
+var appsCmd = &cobra.Command{
+	Use:   "apps",
+	Short: "List the apps captures were taken in",
+	Long: `Lists the apps that captures in the manifest were taken in, from the
+package name at the start of each file name, with the name the app has in
+the headset's library and how many captures came from it.
+
+Names are looked up on the headset when media is pulled. Use --refresh to
+look them up again for apps already pulled from, or 'fetchquest apps label'
+to name an app yourself; names you set are never replaced by the headset's.
+
+These names are what {app} puts in layouts and what --app matches in ls,
+sync and clean.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		if appsRefresh {
+			if err := requireDevices()(cmd, args); err != nil {
+				return err
+			}
+			if err := refreshAppLabels(db); err != nil {
+				return err
+			}
+		}
+
+		apps, err := db.Apps()
+		if err != nil {
+			return err
+		}
+		if len(apps) == 0 {
+			fmt.Println("No captures from known apps yet.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "PACKAGE\tNAME\tFILES\tNAMED BY")
+		for _, a := range apps {
+			label, source := a.Label, a.Source
+			if label == "" {
+				label, source = "-", "-"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", a.Package, label, a.Files, source)
+		}
+		return tw.Flush()
+	},
+}
+
+var appsLabelCmd = &cobra.Command{
+	Use:   "label <package> <name>",
+	Short: "Set the name an app is shown and filed under",
+	Long: `Sets the name used for an app's captures, for apps the headset doesn't
+name or names unhelpfully. Use --clear to forget it, so the headset's name
+is used again after the next pull or 'fetchquest apps --refresh'.
+
+Files already pulled stay where they are: run 'fetchquest relayout' to
+move them if your layout uses {app}.
+
+Examples:
+  fetchquest apps label com.example.mygame "My Game (QA build)"
+  fetchquest apps label --clear com.example.mygame`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if appsClear {
+			return cobra.ExactArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		pkg := args[0]
+		if appsClear {
+			if err := db.DeleteAppLabel(pkg); err != nil {
+				return err
+			}
+			fmt.Printf("Cleared the name of %s\n", pkg)
+			return nil
+		}
+		if err := db.SetAppLabel(pkg, args[1], manifest.LabelFromUser); err != nil {
+			return err
+		}
+		fmt.Printf("%s is now shown as %q\n", pkg, args[1])
+		return nil
+	},
+}
+
+// refreshAppLabels looks up app names on the chosen headset, or every
+// connected one.
+func refreshAppLabels(db *manifest.DB) error {
+	adbClient := adb.NewClient()
+	var devices []adb.Device
+	if appsDevice != "" {
+		d, err := adbClient.FindDevice(appsDevice)
+		if err != nil {
+			return err
+		}
+		devices = []adb.Device{d}
+	} else {
+		all, err := adbClient.Devices()
+		if err != nil {
+			return err
+		}
+		for _, d := range adb.UniqueDevices(all) {
+			if d.IsOnline() {
+				devices = append(devices, d)
+			}
+		}
+	}
+	if len(devices) == 0 {
+		return fmt.Errorf("no connected devices to look up app names on")
+	}
+
+	for _, d := range devices {
+		n, err := qsync.RefreshAppLabels(adbClient, db, d, appsAll)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Error looking up app names on %s: %v\n", d.ID, err)
+		}
+		fmt.Printf("Device %s: %d app name(s) found\n", d.ID, n)
+	}
+	fmt.Println()
+	return nil
+}
+
+func init() {
+	appsCmd.Flags().BoolVar(&appsRefresh, "refresh", false, "Look up app names on the connected headset(s)")
+	appsCmd.Flags().BoolVar(&appsAll, "all", false, "With --refresh, also look up apps that already have a name")
+	appsCmd.Flags().StringVarP(&appsDevice, "device", "d", "", "With --refresh, device serial (default: all)")
+	appsLabelCmd.Flags().BoolVar(&appsClear, "clear", false, "Forget the app's name instead of setting one")
+	appsCmd.AddCommand(appsLabelCmd)
+	rootCmd.AddCommand(appsCmd)
+}
@@ -3,32 +3,51 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"strings"
+	"time"
 
+	"github.com/FluidXR/fetchquest/internal/backup"
 	"github.com/FluidXR/fetchquest/internal/config"
 	"github.com/FluidXR/fetchquest/internal/manifest"
 	"github.com/FluidXR/fetchquest/internal/rclone"
 )
 
-// backupManifest copies the manifest DB to all configured destinations.
+// backupManifest merges in other computers' backups, then snapshots the
+// manifest DB and uploads it to all configured destinations as a new
+// timestamped version.
 func backupManifest(db *manifest.DB, cfg *config.Config, rc *rclone.Client) {
 	if len(cfg.Destinations) == 0 {
 		return
 	}
-	dbPath := db.Path()
-	if _, err := os.Stat(dbPath); err != nil {
+	fmt.Println("\n=== Backing up manifest ===")
+	machine, err := config.MachineID()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
 		return
 	}
-	fmt.Println("\n=== Backing up manifest ===")
-	for _, dest := range cfg.Destinations {
-		remote := dest.RcloneRemote
-		if !strings.HasSuffix(remote, "/") {
-			remote += "/"
+	// Fold in other computers' syncs first so every backup has them all.
+	merged, err := backup.MergeOthers(rc, db, cfg.Destinations, machine)
+	for _, m := range merged {
+		if m.Result.Changed() {
+			fmt.Printf("  Merged syncs from %s (%d new files, %d new syncs)\n",
+				m.Version.Machine, m.Result.FilesAdded, m.Result.SyncsAdded)
 		}
-		remote += ".fetchquest/manifest.db"
-		fmt.Printf("  %s -> %s\n", dest.Name, remote)
-		if err := rc.Copy(dbPath, remote); err != nil {
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+	}
+	snap, err := backup.Snapshot(db)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Warning: %v\n", err)
+		return
+	}
+	defer os.Remove(snap)
+	now := time.Now()
+	for _, dest := range cfg.Destinations {
+		name, err := backup.Upload(rc, dest, snap, machine, now, cfg.BackupKeep)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "  Warning: manifest backup to %s failed: %v\n", dest.Name, err)
+			continue
 		}
+		fmt.Printf("  %s -> %s\n", dest.Name, dest.Remote(name))
 	}
 }
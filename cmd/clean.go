@@ -10,6 +10,7 @@ import (
 	"github.com/FluidXR/fetchquest/internal/adb"
 	"github.com/FluidXR/fetchquest/internal/config"
 	"github.com/FluidXR/fetchquest/internal/manifest"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
 
 	"github.com/spf13/cobra"
 )
@@ -20,20 +21,33 @@ var (
 	cleanDryRun  bool
 	cleanAny     bool
 	cleanLocal   bool
+	cleanFree    string
+	cleanApps    []string
 )
 
 var cleanCmd = &cobra.Command{
 	Use:               "clean",
 	Short:             "Delete already-synced media from Quest(s)",
-	PersistentPreRunE: requireDeps(),
-	Long: `Removes files from Quest that have been confirmed synced to all configured destinations.
+	PersistentPreRunE: requireDevices(),
+	Long: `Removes files from Quest whose uploads have been verified (size, plus SHA-256
+where the backend supports it) at all configured destinations.
 Use --any to delete files synced to at least one destination instead.
 Use --local to clean the local sync directory instead of the Quest.
+Use --free 20GB to delete only the oldest synced files, until that much
+space would be free on the headset.
+Use --app to only delete captures from some apps, by name or package.
 Shows a dry-run summary first unless --confirm is passed.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if cleanLocal {
 			return cleanLocalDir()
 		}
+		var target int64
+		if cleanFree != "" {
+			var err error
+			if target, err = parseSize(cleanFree); err != nil {
+				return err
+			}
+		}
 		cfg, err := config.Load()
 		if err != nil {
 			return fmt.Errorf("load config: %w", err)
@@ -47,53 +61,79 @@ Shows a dry-run summary first unless --confirm is passed.`,
 		}
 		defer db.Close()
 
+		apps, err := qsync.NewAppFilter(db, cleanApps)
+		if err != nil {
+			return err
+		}
+
 		adbClient := adb.NewClient()
 		destNames := make([]string, len(cfg.Destinations))
 		for i, d := range cfg.Destinations {
 			destNames[i] = d.Name
 		}
 
-		var serials []string
+		var devices []adb.Device
 		if cleanDevice != "" {
-			serials = []string{cleanDevice}
+			d, err := adbClient.FindDevice(cleanDevice)
+			if err != nil {
+				return err
+			}
+			devices = []adb.Device{d}
 		} else {
-			devices, err := adbClient.Devices()
+			all, err := adbClient.Devices()
 			if err != nil {
 				return err
 			}
-			for _, d := range devices {
+			for _, d := range adb.UniqueDevices(all) {
 				if d.IsOnline() {
-					serials = append(serials, d.Serial)
+					devices = append(devices, d)
 				}
 			}
 		}
 
-		if len(serials) == 0 {
+		if len(devices) == 0 {
 			fmt.Println("No connected devices found.")
 			return nil
 		}
 
-		for _, serial := range serials {
+		for _, d := range devices {
 			var entries []manifest.Entry
 			var err error
 			if cleanAny {
-				entries, err = db.GetAnySyncedFiles(serial)
+				entries, err = db.GetAnySyncedFiles(d.ID)
 			} else {
-				entries, err = db.GetFullySyncedFiles(serial, destNames)
+				entries, err = db.GetFullySyncedFiles(d.ID, destNames)
 			}
 			if err != nil {
-				return fmt.Errorf("get synced files for %s: %w", serial, err)
+				return fmt.Errorf("get synced files for %s: %w", d.ID, err)
+			}
+			entries = apps.Filter(entries)
+			if cleanFree != "" {
+				if entries, err = selectToFree(adbClient, cfg, d, entries, target); err != nil {
+					return err
+				}
 			}
 
 			if len(entries) == 0 {
-				fmt.Printf("Device %s: no files eligible for cleanup\n", serial)
+				fmt.Printf("Device %s: no files eligible for cleanup\n", d.ID)
 				continue
 			}
 
-			fmt.Printf("\nDevice %s: %d files eligible for cleanup:\n", serial, len(entries))
+			var total int64
 			for _, e := range entries {
-				fmt.Printf("  %s (%d bytes)\n", e.RemotePath, e.Size)
+				total += e.Size
 			}
+			fmt.Printf("\nDevice %s: %d files eligible for cleanup (%s):\n", d.ID, len(entries), formatBytes(total))
+			verified := 0
+			for _, e := range entries {
+				status := "uploaded, not hash-checked everywhere"
+				if ok, _ := db.IsHashVerified(e.ID, destNames); ok {
+					status = "hash-verified everywhere"
+					verified++
+				}
+				fmt.Printf("  %s (%d bytes, %s)\n", e.RemotePath, e.Size, status)
+			}
+			fmt.Printf("  %d of %d hash-verified on every destination\n", verified, len(entries))
 
 			if cleanDryRun {
 				fmt.Println("  (dry run — no files deleted)")
@@ -113,18 +153,43 @@ Shows a dry-run summary first unless --confirm is passed.`,
 
 			deleted := 0
 			for _, e := range entries {
-				if err := adbClient.Remove(serial, e.RemotePath); err != nil {
+				if err := adbClient.Remove(d.Serial, e.RemotePath); err != nil {
 					fmt.Fprintf(os.Stderr, "  Error deleting %s: %v\n", e.RemotePath, err)
 					continue
 				}
 				deleted++
 			}
-			fmt.Printf("  Deleted %d files from device %s\n", deleted, serial)
+			fmt.Printf("  Deleted %d files from device %s\n", deleted, d.ID)
 		}
 		return nil
 	},
 }
 
+// selectToFree narrows entries to the oldest files still on the device
+// whose deletion brings free space up to target bytes.
+func selectToFree(adbClient *adb.Client, cfg *config.Config, d adb.Device, entries []manifest.Entry, target int64) ([]manifest.Entry, error) {
+	du, err := adbClient.DiskUsage(d.Serial, qsync.StoragePath)
+	if err != nil {
+		return nil, err
+	}
+	need := target - du.Free
+	fmt.Printf("\nDevice %s: %s free, target %s\n", d.ID, formatBytes(du.Free), formatBytes(target))
+	if need <= 0 {
+		fmt.Println("  Already enough free space.")
+		return nil, nil
+	}
+	onDevice, err := qsync.OnDevice(adbClient, cfg, d, entries)
+	if err != nil {
+		return nil, err
+	}
+	picked, freed := qsync.SelectOldest(onDevice, need)
+	if freed < need {
+		fmt.Printf("  Only %s of synced media can be cleaned — %s short of the target.\n",
+			formatBytes(freed), formatBytes(need-freed))
+	}
+	return picked, nil
+}
+
 // isCloudSyncedDir checks if a path is inside a known cloud sync folder.
 // Returns the name of the service if detected, or empty string if not.
 func isCloudSyncedDir(dir string) string {
@@ -193,6 +258,11 @@ func cleanLocalDir() error {
 	if err != nil {
 		return fmt.Errorf("get synced local files: %w", err)
 	}
+	apps, err := qsync.NewAppFilter(db, cleanApps)
+	if err != nil {
+		return err
+	}
+	entries = apps.Filter(entries)
 
 	if len(entries) == 0 {
 		fmt.Println("No local files eligible for cleanup.")
@@ -253,5 +323,7 @@ func init() {
 	cleanCmd.Flags().BoolVar(&cleanDryRun, "dry-run", false, "Show what would be deleted without deleting")
 	cleanCmd.Flags().BoolVar(&cleanAny, "any", false, "Delete files synced to at least one destination (default: all)")
 	cleanCmd.Flags().BoolVar(&cleanLocal, "local", false, "Clean local sync directory instead of Quest")
+	cleanCmd.Flags().StringSliceVar(&cleanApps, "app", nil, "Only delete captures from this app (name or package; repeatable)")
+	cleanCmd.Flags().StringVar(&cleanFree, "free", "", "Delete the oldest synced files until this much space is free (e.g. 20GB)")
 	rootCmd.AddCommand(cleanCmd)
 }
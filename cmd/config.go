@@ -5,11 +5,16 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
+	"github.com/FluidXR/fetchquest/internal/backup"
 	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
 	"github.com/FluidXR/fetchquest/internal/rclone"
 
 	"github.com/spf13/cobra"
@@ -25,6 +30,12 @@ var configCmd = &cobra.Command{
 		}
 		fmt.Printf("Config file: %s\n\n", config.ConfigPath())
 		fmt.Printf("Sync directory: %s\n", cfg.SyncDir)
+		fmt.Printf("Layout: %s\n", cfg.LocalLayout())
+		if cfg.MinBattery > 0 {
+			fmt.Printf("Minimum battery: %d%% (unless charging)\n", cfg.MinBattery)
+		} else {
+			fmt.Printf("Minimum battery: off\n")
+		}
 		fmt.Printf("Media paths:\n")
 		for _, p := range cfg.MediaPaths {
 			fmt.Printf("  - %s\n", p)
@@ -35,6 +46,9 @@ var configCmd = &cobra.Command{
 		}
 		for _, d := range cfg.Destinations {
 			fmt.Printf("  - %s: %s\n", d.Name, d.RcloneRemote)
+			if d.Layout != "" {
+				fmt.Printf("    layout: %s\n", d.Layout)
+			}
 		}
 		fmt.Printf("\nDevices:\n")
 		if len(cfg.Devices) == 0 {
@@ -580,13 +594,130 @@ var configSetWiFiCmd = &cobra.Command{
 	},
 }
 
+var configSetMinBatteryCmd = &cobra.Command{
+	Use:   "set-min-battery <percent>",
+	Short: "Don't sync a headset below this charge unless it's charging (0 to disable)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		percent, err := strconv.Atoi(strings.TrimSuffix(args[0], "%"))
+		if err != nil || percent < 0 || percent > 100 {
+			return fmt.Errorf("invalid percentage %q", args[0])
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		cfg.MinBattery = percent
+		if err := config.Save(cfg); err != nil {
+			return err
+		}
+		if percent == 0 {
+			fmt.Println("Battery check disabled.")
+		} else {
+			fmt.Printf("Set minimum battery: %d%%\n", percent)
+		}
+		return nil
+	},
+}
+
+var layoutDest string
+
+var configSetLayoutCmd = &cobra.Command{
+	Use:   "set-layout <template>",
+	Short: "Set the folder layout for synced files",
+	Long: `Sets the path template files are stored under, in the sync directory or,
+with --dest, on one destination only. Destinations without their own layout
+mirror the sync directory. Use "default" to go back to the default.
+
+Placeholders:
+  {device_nickname}  headset nickname, or its serial if it has none
+  {device_serial}    headset serial
+  {year} {month} {day}  when the file was recorded
+  {type}             Videos, Screenshots, Photos or Other
+  {app}              app the capture was taken in, by name (see 'fetchquest apps')
+  {app_package}      the app's package, e.g. com.beatgames.beatsaber
+  {name}             file name (required, last)
+
+New files use the new layout. Run 'fetchquest relayout' to move
+files that are already synced.
+
+Examples:
+  fetchquest config set-layout "{device_nickname}/{year}/{month}/{type}/{name}"
+  fetchquest config set-layout --dest gdrive "{app}/{name}"`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		template := args[0]
+		if template == "default" {
+			template = ""
+		} else if err := config.ValidateLayout(template); err != nil {
+			return err
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+		if layoutDest == "" {
+			cfg.Layout = template
+		} else {
+			found := false
+			for i := range cfg.Destinations {
+				if cfg.Destinations[i].Name == layoutDest {
+					cfg.Destinations[i].Layout = template
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("destination %q not found", layoutDest)
+			}
+		}
+		if err := config.Save(cfg); err != nil {
+			return err
+		}
+
+		switch {
+		case layoutDest == "":
+			fmt.Printf("Set layout: %s\n", cfg.LocalLayout())
+		case template == "":
+			fmt.Printf("%s now mirrors the sync directory layout.\n", layoutDest)
+		default:
+			fmt.Printf("Set layout for %s: %s\n", layoutDest, template)
+		}
+		fmt.Println("Run 'fetchquest relayout' to move files that are already synced.")
+		return nil
+	},
+}
+
+var (
+	restoreList    bool
+	restoreVersion string
+)
+
+// restoreCandidate is one manifest backup that config restore can install.
+type restoreCandidate struct {
+	dest    config.Destination
+	version backup.Version
+}
+
 var configRestoreCmd = &cobra.Command{
 	Use:   "restore [destination-name]",
 	Short: "Restore manifest DB from a backup on a destination",
-	Long: `Downloads the manifest DB backup from a configured rclone destination.
-If no destination is specified, tries each one until a backup is found.
+	Long: `Lists the manifest backups kept on your rclone destinations and restores
+the one you pick (the newest by default). If no destination is specified,
+backups from every destination are listed.
 
-Example: fetchquest config restore my-nas`,
+Each sync keeps timestamped backups under .fetchquest/backups/<machine>/ on
+every destination (10 per computer by default; change with backup_keep in
+config.yaml).
+
+Restoring replaces the local manifest. To combine another computer's
+manifest with this one instead, use 'fetchquest manifest merge'.
+
+Examples:
+  fetchquest config restore --list
+  fetchquest config restore my-nas
+  fetchquest config restore --version 20250101-120000`,
 	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
@@ -601,20 +732,7 @@ Example: fetchquest config restore my-nas`,
 		configDir := config.ConfigDir()
 		localDB := filepath.Join(configDir, "manifest.db")
 
-		// Check if local manifest already exists
-		if _, err := os.Stat(localDB); err == nil {
-			fmt.Printf("Warning: local manifest already exists at %s\n", localDB)
-			fmt.Print("Overwrite? [y/N] ")
-			var answer string
-			fmt.Scanln(&answer)
-			answer = strings.TrimSpace(strings.ToLower(answer))
-			if answer != "y" && answer != "yes" {
-				fmt.Println("Aborted.")
-				return nil
-			}
-		}
-
-		// Determine which destinations to try
+		// Determine which destinations to look at
 		var dests []config.Destination
 		if len(args) > 0 {
 			name := args[0]
@@ -631,36 +749,119 @@ Example: fetchquest config restore my-nas`,
 			dests = cfg.Destinations
 		}
 
-		if err := os.MkdirAll(configDir, 0o755); err != nil {
-			return fmt.Errorf("create config dir: %w", err)
+		candidates := listRestoreCandidates(rc, dests)
+		if len(candidates) == 0 {
+			return fmt.Errorf("no manifest backup found on any destination")
 		}
 
-		for _, dest := range dests {
-			remote := dest.RcloneRemote
-			if !strings.HasSuffix(remote, "/") {
-				remote += "/"
+		fmt.Println("Manifest backups (newest first):")
+		for i, c := range candidates {
+			machine := c.version.Machine
+			if machine == "" {
+				machine = "-"
 			}
-			remote += ".fetchquest/manifest.db"
+			fmt.Printf("  %2d. %s  %-10s %-20s %8s\n", i+1, c.version.Time.Local().Format("2006-01-02 15:04:05"),
+				c.dest.Name, machine, formatBytes(c.version.Size))
+		}
+		if restoreList {
+			return nil
+		}
 
-			fmt.Printf("Trying %s (%s)...\n", dest.Name, remote)
-			if err := rc.CopyFrom(remote, localDB); err != nil {
-				fmt.Printf("  Not found or failed: %v\n", err)
-				continue
+		reader := bufio.NewReader(os.Stdin)
+		var pick restoreCandidate
+		if restoreVersion != "" {
+			found := false
+			for _, c := range candidates {
+				if strings.Contains(path.Base(c.version.Path), restoreVersion) {
+					pick, found = c, true
+					break
+				}
 			}
-			fmt.Printf("Manifest restored from %s to %s\n", dest.Name, localDB)
-			return nil
+			if !found {
+				return fmt.Errorf("no backup matching %q", restoreVersion)
+			}
+		} else {
+			fmt.Print("Restore which backup? [1] ")
+			line, _ := reader.ReadString('\n')
+			n := 1
+			if line = strings.TrimSpace(line); line != "" {
+				if n, err = strconv.Atoi(line); err != nil || n < 1 || n > len(candidates) {
+					return fmt.Errorf("invalid choice %q", line)
+				}
+			}
+			pick = candidates[n-1]
 		}
 
-		return fmt.Errorf("no manifest backup found on any destination")
+		// Check if local manifest already exists
+		if _, err := os.Stat(localDB); err == nil {
+			fmt.Printf("Warning: local manifest already exists at %s\n", localDB)
+			fmt.Print("Overwrite? [y/N] ")
+			answer, _ := reader.ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			if answer != "y" && answer != "yes" {
+				fmt.Println("Aborted.")
+				return nil
+			}
+		}
+
+		if err := os.MkdirAll(configDir, 0o755); err != nil {
+			return fmt.Errorf("create config dir: %w", err)
+		}
+
+		// Download next to the live manifest, then swap it in once it checks out.
+		download := localDB + ".restore"
+		defer os.Remove(download)
+		fmt.Printf("Downloading %s from %s...\n", pick.version.Path, pick.dest.Name)
+		if err := backup.Download(rc, pick.dest, pick.version.Path, download); err != nil {
+			return fmt.Errorf("download backup: %w", err)
+		}
+		if err := manifest.Restore(configDir, download); err != nil {
+			return fmt.Errorf("unusable backup (try another with --version): %w", err)
+		}
+		fmt.Printf("Manifest restored from %s to %s\n", pick.dest.Name, localDB)
+		return nil
 	},
 }
 
+// listRestoreCandidates collects the manifest backups on dests, newest
+// first. Destinations that only have the single backup written by older
+// versions contribute that instead.
+func listRestoreCandidates(rc *rclone.Client, dests []config.Destination) []restoreCandidate {
+	var candidates []restoreCandidate
+	for _, dest := range dests {
+		versions, err := backup.List(rc, dest)
+		if err != nil {
+			fmt.Printf("  %s: %v\n", dest.Name, err)
+			continue
+		}
+		if len(versions) == 0 {
+			obj, err := rc.Stat(dest.Remote(backup.LegacyPath))
+			if err != nil {
+				continue
+			}
+			versions = []backup.Version{{Path: backup.LegacyPath, Time: obj.ModTime, Size: obj.Size}}
+		}
+		for _, v := range versions {
+			candidates = append(candidates, restoreCandidate{dest: dest, version: v})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].version.Time.After(candidates[j].version.Time)
+	})
+	return candidates
+}
+
 func init() {
 	configCmd.AddCommand(configInitCmd)
 	configCmd.AddCommand(configNicknameCmd)
 	configCmd.AddCommand(configAddDestCmd)
 	configCmd.AddCommand(configRemoveDestCmd)
 	configCmd.AddCommand(configSetWiFiCmd)
+	configCmd.AddCommand(configSetMinBatteryCmd)
+	configSetLayoutCmd.Flags().StringVar(&layoutDest, "dest", "", "Set the layout for this destination only")
+	configCmd.AddCommand(configSetLayoutCmd)
+	configRestoreCmd.Flags().BoolVar(&restoreList, "list", false, "List available backups without restoring")
+	configRestoreCmd.Flags().StringVar(&restoreVersion, "version", "", "Restore the backup whose name contains this timestamp (e.g. 20250101-120000)")
 	configCmd.AddCommand(configRestoreCmd)
 	rootCmd.AddCommand(configCmd)
 }
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Keep running and push queued files as destinations become reachable",
+	Long: `Runs in the foreground and keeps pushing pulled files that haven't reached
+every destination yet. Destinations that are unreachable (a NAS that is
+switched off, no network) are re-checked with increasing back-off, and their
+queue is drained as soon as they come back.
+
+Run 'fetchquest daemon status' from another terminal to see what is queued.`,
+	// Only rclone is needed, and nothing may wait on a prompt.
+	PreRunE: func(cmd *cobra.Command, args []string) error { return checkDep("rclone") },
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if len(cfg.Destinations) == 0 {
+			return fmt.Errorf("no destinations configured — run 'fetchquest config add-dest' first")
+		}
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		if st, err := db.GetDaemonStatus(); err == nil && st != nil &&
+			st.PID != os.Getpid() && time.Since(st.HeartbeatAt) < qsync.HeartbeatStale {
+			return fmt.Errorf("a daemon is already running (pid %d)", st.PID)
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		rc := rclone.NewClient()
+		d := &qsync.Daemon{
+			Rclone:   rc,
+			Manifest: db,
+			AfterPush: func(cfg *config.Config) {
+				backupManifest(db, cfg, rc)
+			},
+		}
+		fmt.Println("Daemon running — pushing queued files as destinations come online. (Ctrl+C to stop)")
+		if err := d.Run(ctx); err != nil && ctx.Err() == nil {
+			return err
+		}
+		fmt.Println("\nStopped.")
+		return nil
+	},
+}
+
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the daemon's state and what is still queued per destination",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		st, err := db.GetDaemonStatus()
+		if err != nil {
+			return err
+		}
+		if st != nil && time.Since(st.HeartbeatAt) < qsync.HeartbeatStale {
+			fmt.Printf("Daemon: running (pid %d, since %s)\n", st.PID, st.StartedAt.Local().Format("2006-01-02 15:04"))
+		} else {
+			fmt.Println("Daemon: not running")
+		}
+
+		names := make([]string, len(cfg.Destinations))
+		for i, d := range cfg.Destinations {
+			names[i] = d.Name
+		}
+		statuses, err := db.GetDestStatuses(names)
+		if err != nil {
+			return err
+		}
+		if len(statuses) == 0 {
+			fmt.Println("No destinations configured.")
+			return nil
+		}
+		fmt.Println()
+		for _, s := range statuses {
+			state := "unknown"
+			if s.CheckedAt != nil {
+				state = "reachable"
+				if !s.Reachable {
+					state = fmt.Sprintf("unreachable (%d failed checks)", s.Failures)
+				}
+			}
+			fmt.Printf("%s: %s, %d file(s) queued\n", s.Destination, state, s.Queued)
+			if s.CheckedAt != nil {
+				fmt.Printf("  Last checked: %s\n", s.CheckedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			if s.PushedAt != nil {
+				fmt.Printf("  Last push:    %s\n", s.PushedAt.Local().Format("2006-01-02 15:04:05"))
+			}
+			if s.NextRetry != nil && !s.Reachable {
+				fmt.Printf("  Next retry:   %s\n", s.NextRetry.Local().Format("2006-01-02 15:04:05"))
+			}
+		}
+		return nil
+	},
+}
+
+func init() {
+	daemonCmd.AddCommand(daemonStatusCmd)
+	rootCmd.AddCommand(daemonCmd)
+}
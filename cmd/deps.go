@@ -10,6 +10,8 @@ import (
 
 	"github.com/FluidXR/fetchquest/internal/adb"
 	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
 )
 
 type dependency struct {
@@ -39,6 +41,20 @@ var dependencies = []dependency{
 	},
 }
 
+// checkDep verifies that one tool is installed, without offering to
+// install it, for commands that may run unattended.
+func checkDep(binary string) error {
+	if _, err := exec.LookPath(binary); err == nil {
+		return nil
+	}
+	for _, dep := range dependencies {
+		if cmd, ok := dep.installCmd[runtime.GOOS]; ok && dep.binary == binary {
+			return fmt.Errorf("%s is required but not installed — install it with: %s", binary, cmd)
+		}
+	}
+	return fmt.Errorf("%s is required but not installed", binary)
+}
+
 // checkDeps verifies that required external tools are installed.
 // Returns nil if all deps are present or user declines to install.
 func checkDeps() error {
@@ -114,14 +130,27 @@ func checkNewDevices() {
 		return
 	}
 
-	reader := bufio.NewReader(os.Stdin)
+	if cfg.Devices == nil {
+		cfg.Devices = make(map[string]config.DeviceConfig)
+	}
+
+	// Move anything recorded under a WiFi ip:port to the hardware serial.
 	changed := false
+	if db, err := manifest.Open(config.ConfigDir()); err == nil {
+		changed, err = qsync.AdoptDeviceIdentities(db, cfg, devices)
+		db.Close()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not migrate device records: %v\n", err)
+		}
+	}
 
-	for _, d := range devices {
+	reader := bufio.NewReader(os.Stdin)
+
+	for _, d := range adb.UniqueDevices(devices) {
 		if !d.IsOnline() {
 			continue
 		}
-		if _, known := cfg.Devices[d.Serial]; known {
+		if _, known := cfg.Devices[d.ID]; known {
 			continue
 		}
 
@@ -129,19 +158,16 @@ func checkNewDevices() {
 		if model == "" {
 			model = "unknown model"
 		}
-		fmt.Printf("\nNew device detected: %s (%s)\n", d.Serial, model)
+		fmt.Printf("\nNew device detected: %s (%s)\n", d.ID, model)
 		fmt.Print("Give it a nickname (or press Enter to skip): ")
 		name, _ := reader.ReadString('\n')
 		name = strings.TrimSpace(name)
 
-		if cfg.Devices == nil {
-			cfg.Devices = make(map[string]config.DeviceConfig)
-		}
-		dc := cfg.Devices[d.Serial]
+		dc := cfg.Devices[d.ID]
 		if name != "" {
 			dc.Nickname = name
 		}
-		cfg.Devices[d.Serial] = dc
+		cfg.Devices[d.ID] = dc
 		changed = true
 	}
 
@@ -1,19 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/FluidXR/fetchquest/internal/adb"
 	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/discovery"
 	"github.com/FluidXR/fetchquest/internal/manifest"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
 
 	"github.com/spf13/cobra"
 )
 
+var devicesDiscover bool
+
 var devicesCmd = &cobra.Command{
 	Use:               "devices",
 	Short:             "List connected Quests and their sync status",
-	PersistentPreRunE: requireDeps(),
+	PersistentPreRunE: requireDevices(),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
@@ -28,6 +33,9 @@ var devicesCmd = &cobra.Command{
 
 		if len(devices) == 0 {
 			fmt.Println("No devices connected.")
+			if devicesDiscover {
+				return discoverDevices(cfg, devices)
+			}
 			return nil
 		}
 
@@ -39,9 +47,9 @@ var devicesCmd = &cobra.Command{
 
 		numDests := len(cfg.Destinations)
 
-		for _, d := range devices {
+		for _, d := range adb.UniqueDevices(devices) {
 			nickname := ""
-			if dc, ok := cfg.Devices[d.Serial]; ok && dc.Nickname != "" {
+			if dc, ok := cfg.Devices[d.ID]; ok && dc.Nickname != "" {
 				nickname = fmt.Sprintf(" (%s)", dc.Nickname)
 			}
 
@@ -51,20 +59,92 @@ var devicesCmd = &cobra.Command{
 			}
 
 			fmt.Printf("%-20s %s  [%s] [%s]%s\n",
-				d.Serial, d.Model, d.ConnType, status, nickname)
+				d.ID, d.Model, d.ConnType, status, nickname)
+			if d.Serial != d.ID {
+				fmt.Printf("  Connected as %s\n", d.Serial)
+			}
 
 			if d.IsOnline() {
-				stats, err := db.GetDeviceStats(d.Serial, numDests)
+				stats, err := db.GetDeviceStats(d.ID, numDests)
 				if err == nil && stats.TotalFiles > 0 {
 					fmt.Printf("  Files tracked: %d | Pulled: %d | Fully synced: %d\n",
 						stats.TotalFiles, stats.PulledFiles, stats.SyncedFiles)
 				}
+				if st, err := qsync.DeviceStorage(adbClient, db, cfg, d); err == nil {
+					fmt.Printf("  Storage: %s free of %s | Media: %d files, %s | Reclaimable now: %d files, %s\n",
+						formatBytes(st.Free), formatBytes(st.Total),
+						st.MediaFiles, formatBytes(st.MediaBytes),
+						st.ReclaimableFiles, formatBytes(st.ReclaimableBytes))
+				}
+				if b, err := adbClient.Battery(d.Serial); err == nil {
+					charging := ""
+					if b.Charging {
+						charging = " (charging)"
+					} else if cfg.MinBattery > 0 && b.Level < cfg.MinBattery {
+						charging = fmt.Sprintf(" (below %d%%, plug in to sync)", cfg.MinBattery)
+					}
+					fmt.Printf("  Battery: %d%%%s\n", b.Level, charging)
+				}
 			}
 		}
+		if devicesDiscover {
+			return discoverDevices(cfg, devices)
+		}
 		return nil
 	},
 }
 
+// discoverDevices lists headsets advertising wireless ADB on the LAN and
+// refreshes the saved address of any known headset that moved.
+func discoverDevices(cfg *config.Config, attached []adb.Device) error {
+	fmt.Println("\nSearching the network...")
+	svcs, err := discovery.Browse(context.Background())
+	if err != nil {
+		return fmt.Errorf("discover devices: %w", err)
+	}
+	if len(svcs) == 0 {
+		fmt.Println("No headsets found on the network.")
+		return nil
+	}
+
+	connected := make(map[string]bool)
+	for _, d := range attached {
+		if d.IsOnline() {
+			connected[d.ID] = true
+			connected[d.Serial] = true
+		}
+	}
+	for _, s := range svcs {
+		kind := "adb tcpip"
+		if s.Type == discovery.TLSConnectService {
+			kind = "wireless debugging"
+		}
+		note := ""
+		if dc, ok := cfg.Devices[s.Serial]; ok && dc.Nickname != "" {
+			note = fmt.Sprintf(" (%s)", dc.Nickname)
+		}
+		if connected[s.Serial] || connected[s.Addr()] {
+			note += " connected"
+		}
+		name := s.Serial
+		if name == "" {
+			name = s.Instance
+		}
+		fmt.Printf("%-20s %-21s [%s]%s\n", name, s.Addr(), kind, note)
+	}
+
+	if changed := discovery.UpdateConfig(cfg, svcs); len(changed) > 0 {
+		if err := config.Save(cfg); err != nil {
+			return err
+		}
+		for _, serial := range changed {
+			fmt.Printf("Updated WiFi address for %s: %s\n", serial, cfg.Devices[serial].WiFiAddr())
+		}
+	}
+	return nil
+}
+
 func init() {
+	devicesCmd.Flags().BoolVar(&devicesDiscover, "discover", false, "Also search the local network for headsets with wireless ADB enabled")
 	rootCmd.AddCommand(devicesCmd)
 }
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	importDevice string
+	importMode   string
+	importDryRun bool
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import <dir>",
+	Short: "Import a folder of Quest media copied off a headset by hand",
+	Long: `Brings videos and screenshots you copied off a headset yourself into the
+sync directory and the manifest, so they get pushed to your destinations
+like anything FetchQuest pulled.
+
+Files are identified by their SHA-256, and anything the manifest already has
+is skipped. Files that are still on a connected headset (same name, size
+and hash) are recorded as that headset's file: they won't be pulled again,
+and 'clean' can delete them from the headset once they've been pushed.
+Other files are recorded under --device, or as "import" if it isn't given.
+
+--mode picks how files get into the sync directory: copy (default), move,
+or link (hard link; the folder must be on the same drive).`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		mode := qsync.ImportMode(importMode)
+		switch mode {
+		case qsync.ImportCopy, qsync.ImportMove, qsync.ImportLink:
+		default:
+			return fmt.Errorf("unknown --mode %q (use copy, move or link)", importMode)
+		}
+		if fi, err := os.Stat(args[0]); err != nil {
+			return err
+		} else if !fi.IsDir() {
+			return fmt.Errorf("%s is not a directory", args[0])
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		adbClient := adb.NewClient()
+		im := &qsync.Importer{
+			ADB:      adbClient,
+			Manifest: db,
+			Config:   cfg,
+			Mode:     mode,
+			Device:   importDevice,
+			DryRun:   importDryRun,
+		}
+		if importDevice != "" {
+			if d, err := adbClient.FindDevice(importDevice); err == nil {
+				im.Devices = []adb.Device{d}
+				im.Device = d.ID
+			} else {
+				fmt.Printf("Device %s is not connected — files won't be matched against it.\n", importDevice)
+			}
+		} else if all, err := adbClient.Devices(); err == nil {
+			for _, d := range adb.UniqueDevices(all) {
+				if d.IsOnline() {
+					im.Devices = append(im.Devices, d)
+				}
+			}
+		}
+		for _, d := range im.Devices {
+			fmt.Printf("Matching against files on %s\n", d.ID)
+		}
+
+		fmt.Printf("Importing %s into %s...\n", args[0], cfg.ExpandSyncDir())
+		result, err := im.Import(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("\nImported: %d files (%d still on a headset)\n", result.Imported, result.Matched)
+		fmt.Printf("Skipped: %d files (already in the manifest)\n", result.Skipped)
+		if result.Ignored > 0 {
+			fmt.Printf("Ignored: %d files (not media)\n", result.Ignored)
+		}
+		for _, e := range result.Errors {
+			fmt.Fprintf(os.Stderr, "  Error: %s\n", e)
+		}
+		if importDryRun {
+			fmt.Println("(dry run — nothing imported)")
+		} else if result.Imported > 0 {
+			fmt.Println("Run 'fetchquest push' to upload them.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	importCmd.Flags().StringVarP(&importDevice, "device", "d", "", "Headset the files came from")
+	importCmd.Flags().StringVar(&importMode, "mode", string(qsync.ImportCopy), "How to bring files in: copy, move or link")
+	importCmd.Flags().BoolVar(&importDryRun, "dry-run", false, "Show what would be imported without importing")
+	rootCmd.AddCommand(importCmd)
+}
@@ -0,0 +1,380 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lsDevice      string
+	lsDestMissing string
+	lsSince       string
+	lsType        string
+	lsApps        []string
+	lsNotSynced   bool
+	lsSort        string
+	lsReverse     bool
+	lsJSON        bool
+	lsCSV         bool
+)
+
+// lsSync is one destination a file was synced to, as printed by ls.
+type lsSync struct {
+	Destination string     `json:"destination"`
+	ObjectPath  string     `json:"object_path"`
+	SyncedAt    time.Time  `json:"synced_at"`
+	VerifiedAt  *time.Time `json:"verified_at"`
+	SHA256      string     `json:"sha256,omitempty"`
+}
+
+// lsFile is one manifest entry as printed by ls.
+type lsFile struct {
+	Device     string     `json:"device"`
+	Nickname   string     `json:"nickname,omitempty"`
+	RemotePath string     `json:"remote_path"`
+	LocalPath  string     `json:"local_path"`
+	Type       string     `json:"type"`
+	App        string     `json:"app,omitempty"`       // package name
+	AppLabel   string     `json:"app_label,omitempty"` // name in the app library
+	Size       int64      `json:"size"`
+	MTime      time.Time  `json:"mtime"`
+	Captured   time.Time  `json:"captured_at"` // capture time if known, else mtime
+	Duration   float64    `json:"duration_seconds,omitempty"`
+	Width      int        `json:"width,omitempty"`
+	Height     int        `json:"height,omitempty"`
+	Codec      string     `json:"codec,omitempty"`
+	PulledAt   *time.Time `json:"pulled_at"`
+	SHA256     string     `json:"sha256,omitempty"`
+	Syncs      []lsSync   `json:"destinations"`
+}
+
+var lsCmd = &cobra.Command{
+	Use:     "ls",
+	Aliases: []string{"status"},
+	Short:   "List files in the manifest and where they've been synced",
+	Long: `Lists every file FetchQuest knows about, with the headset it came from,
+its size, the app it was captured in, when it was captured, the length and
+resolution of videos, when it was pulled, and the destinations it has been
+synced to. Uploads not yet verified at a destination are marked with a "?".
+
+The capture time is read from the file itself where possible, since the
+modification time on the headset changes when files are copied around
+there. Files pulled by older versions can be read with
+'fetchquest manifest probe'. Apps are shown by the name the headset gives
+them (see 'fetchquest apps'), and --app accepts that name or the package.
+
+Examples:
+  fetchquest ls --not-synced
+  fetchquest ls --device "John's Quest 3" --type video --since 7d
+  fetchquest ls --dest-missing gdrive --json
+  fetchquest ls --type video --sort duration -r
+  fetchquest ls --app "Beat Saber" --app com.oculus.vrshell`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if lsJSON && lsCSV {
+			return fmt.Errorf("--json and --csv can't be used together")
+		}
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		var since time.Time
+		if lsSince != "" {
+			if since, err = parseSince(lsSince); err != nil {
+				return err
+			}
+		}
+		if lsDestMissing != "" {
+			found := false
+			for _, d := range cfg.Destinations {
+				found = found || d.Name == lsDestMissing
+			}
+			if !found {
+				return fmt.Errorf("destination %q not found", lsDestMissing)
+			}
+		}
+		mediaType := ""
+		if lsType != "" {
+			if mediaType, err = parseMediaType(lsType); err != nil {
+				return err
+			}
+		}
+		less, err := lsOrder(lsSort)
+		if err != nil {
+			return err
+		}
+
+		apps, err := qsync.NewAppFilter(db, lsApps)
+		if err != nil {
+			return err
+		}
+
+		statuses, err := db.FileStatuses()
+		if err != nil {
+			return err
+		}
+		var files []lsFile
+		for _, st := range statuses {
+			nickname := cfg.Devices[st.DeviceSerial].Nickname
+			if lsDevice != "" && st.DeviceSerial != lsDevice && !strings.EqualFold(nickname, lsDevice) {
+				continue
+			}
+			if lsDestMissing != "" && st.Verified(lsDestMissing) {
+				continue
+			}
+			if lsNotSynced && syncedEverywhere(st, cfg.Destinations) {
+				continue
+			}
+			captured := st.CaptureTime()
+			if !since.IsZero() && captured.Before(since) {
+				continue
+			}
+			t := qsync.MediaType(st.Entry)
+			if mediaType != "" && t != mediaType {
+				continue
+			}
+			if !apps.Match(st.Entry) {
+				continue
+			}
+			f := lsFile{
+				Device:     st.DeviceSerial,
+				Nickname:   nickname,
+				RemotePath: st.RemotePath,
+				LocalPath:  st.LocalPath,
+				Type:       t,
+				App:        st.App,
+				AppLabel:   st.AppLabel,
+				Size:       st.Size,
+				MTime:      time.Unix(st.MTime, 0),
+				Captured:   captured,
+				Duration:   float64(st.DurationMS) / 1000,
+				Width:      st.Width,
+				Height:     st.Height,
+				Codec:      st.Codec,
+				PulledAt:   st.PulledAt,
+				SHA256:     st.SHA256,
+				Syncs:      []lsSync{},
+			}
+			for _, ds := range st.Syncs {
+				f.Syncs = append(f.Syncs, lsSync{ds.Destination, ds.ObjectPath, ds.SyncedAt, ds.VerifiedAt, ds.SHA256})
+			}
+			files = append(files, f)
+		}
+		sort.SliceStable(files, func(i, j int) bool {
+			if lsReverse {
+				return less(files[j], files[i])
+			}
+			return less(files[i], files[j])
+		})
+
+		switch {
+		case lsJSON:
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if files == nil {
+				files = []lsFile{}
+			}
+			return enc.Encode(files)
+		case lsCSV:
+			return writeLsCSV(files)
+		}
+
+		if len(files) == 0 {
+			fmt.Println("No matching files.")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "DEVICE\tFILE\tAPP\tTYPE\tSIZE\tCAPTURED\tLENGTH\tRESOLUTION\tSYNCED TO")
+		var total int64
+		for _, f := range files {
+			device := f.Device
+			if f.Nickname != "" {
+				device = f.Nickname
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", device, path.Base(f.RemotePath), appColumn(f), f.Type,
+				formatBytes(f.Size), f.Captured.Format("2006-01-02 15:04"), formatLength(f.Duration),
+				formatResolution(f.Width, f.Height), syncList(f.Syncs))
+			total += f.Size
+		}
+		tw.Flush()
+		fmt.Printf("\n%d files, %s\n", len(files), formatBytes(total))
+		return nil
+	},
+}
+
+// syncedEverywhere reports whether st has been verified at every
+// configured destination.
+func syncedEverywhere(st manifest.FileStatus, dests []config.Destination) bool {
+	if len(dests) == 0 {
+		return false
+	}
+	for _, d := range dests {
+		if !st.Verified(d.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+// syncList renders destinations for the table, marking unverified uploads.
+func syncList(syncs []lsSync) string {
+	if len(syncs) == 0 {
+		return "-"
+	}
+	names := make([]string, len(syncs))
+	for i, s := range syncs {
+		names[i] = s.Destination
+		if s.VerifiedAt == nil {
+			names[i] += "?"
+		}
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// formatLength renders a duration in seconds as m:ss or h:mm:ss.
+func formatLength(seconds float64) string {
+	if seconds <= 0 {
+		return "-"
+	}
+	s := int(seconds + 0.5)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// appColumn renders the app a file was captured in for the table.
+func appColumn(f lsFile) string {
+	switch {
+	case f.AppLabel != "":
+		return f.AppLabel
+	case f.App != "":
+		return f.App
+	}
+	return "-"
+}
+
+func formatResolution(w, h int) string {
+	if w == 0 || h == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%dx%d", w, h)
+}
+
+func writeLsCSV(files []lsFile) error {
+	w := csv.NewWriter(os.Stdout)
+	w.Write([]string{"device", "nickname", "remote_path", "local_path", "type", "app", "app_label", "size", "mtime", "captured_at",
+		"duration_seconds", "width", "height", "codec", "pulled_at", "sha256", "destinations"})
+	for _, f := range files {
+		pulled := ""
+		if f.PulledAt != nil {
+			pulled = f.PulledAt.Format(time.RFC3339)
+		}
+		var dests []string
+		for _, s := range f.Syncs {
+			if s.VerifiedAt != nil {
+				dests = append(dests, s.Destination)
+			}
+		}
+		sort.Strings(dests)
+		w.Write([]string{f.Device, f.Nickname, f.RemotePath, f.LocalPath, f.Type, f.App, f.AppLabel, strconv.FormatInt(f.Size, 10),
+			f.MTime.Format(time.RFC3339), f.Captured.Format(time.RFC3339), strconv.FormatFloat(f.Duration, 'f', 3, 64),
+			strconv.Itoa(f.Width), strconv.Itoa(f.Height), f.Codec, pulled, f.SHA256, strings.Join(dests, ";")})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// parseSince accepts a date (2006-01-02), an RFC 3339 time, a duration
+// like 36h, or a number of days like 7d.
+func parseSince(s string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", s, time.Local); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil {
+			return time.Now().AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --since %q (use a date like 2025-01-31, or 7d, 36h)", s)
+}
+
+// parseMediaType maps --type values like "video" or "screenshots" to a
+// media type folder name.
+func parseMediaType(s string) (string, error) {
+	t := strings.ToLower(strings.TrimSpace(s))
+	for _, name := range []string{"Videos", "Screenshots", "Photos", "Other"} {
+		if l := strings.ToLower(name); t == l || t+"s" == l {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("invalid --type %q (use video, screenshot, photo or other)", s)
+}
+
+// lsOrder returns the comparison for --sort.
+func lsOrder(key string) (func(a, b lsFile) bool, error) {
+	switch key {
+	case "captured", "time", "":
+		return func(a, b lsFile) bool { return a.Captured.Before(b.Captured) }, nil
+	case "mtime":
+		return func(a, b lsFile) bool { return a.MTime.Before(b.MTime) }, nil
+	case "duration":
+		return func(a, b lsFile) bool { return a.Duration < b.Duration }, nil
+	case "resolution":
+		return func(a, b lsFile) bool { return a.Width*a.Height < b.Width*b.Height }, nil
+	case "name":
+		return func(a, b lsFile) bool { return path.Base(a.RemotePath) < path.Base(b.RemotePath) }, nil
+	case "size":
+		return func(a, b lsFile) bool { return a.Size < b.Size }, nil
+	case "pulled":
+		return func(a, b lsFile) bool {
+			return a.PulledAt != nil && (b.PulledAt == nil || a.PulledAt.Before(*b.PulledAt))
+		}, nil
+	case "device":
+		return func(a, b lsFile) bool { return a.Device < b.Device }, nil
+	case "app":
+		return func(a, b lsFile) bool { return strings.ToLower(appColumn(a)) < strings.ToLower(appColumn(b)) }, nil
+	}
+	return nil, fmt.Errorf("invalid --sort %q (use captured, mtime, duration, resolution, name, size, pulled, device or app)", key)
+}
+
+func init() {
+	lsCmd.Flags().StringVarP(&lsDevice, "device", "d", "", "Only files from this device (serial or nickname)")
+	lsCmd.Flags().StringVar(&lsDestMissing, "dest-missing", "", "Only files not yet verified at this destination")
+	lsCmd.Flags().StringVar(&lsSince, "since", "", "Only files captured since a date (2025-01-31) or duration (7d, 36h)")
+	lsCmd.Flags().StringVar(&lsType, "type", "", "Only this media type: video, screenshot, photo or other")
+	lsCmd.Flags().StringSliceVar(&lsApps, "app", nil, "Only files captured in this app (name or package; repeatable)")
+	lsCmd.Flags().BoolVar(&lsNotSynced, "not-synced", false, "Only files not yet verified at every destination")
+	lsCmd.Flags().StringVar(&lsSort, "sort", "captured", "Sort by captured, mtime, duration, resolution, name, size, pulled, device or app")
+	lsCmd.Flags().BoolVarP(&lsReverse, "reverse", "r", false, "Reverse the sort order")
+	lsCmd.Flags().BoolVar(&lsJSON, "json", false, "Output JSON")
+	lsCmd.Flags().BoolVar(&lsCSV, "csv", false, "Output CSV")
+	rootCmd.AddCommand(lsCmd)
+}
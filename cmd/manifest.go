@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/backup"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Maintain the sync manifest",
+}
+
+var manifestMergeCmd = &cobra.Command{
+	Use:   "merge <file|destination>",
+	Short: "Merge another manifest into this one",
+	Long: `Folds another manifest into the local one instead of replacing it, so
+computers syncing to the same destinations agree on what has been synced.
+
+The argument is either a manifest file, or the name of a destination, in
+which case the newest backup from every other computer on it is merged.
+
+Files are matched by headset and path on the headset, and syncs by file and
+destination. The most recent pull wins for a file's size and hash, a sync
+verified at the destination wins over an unverified one, and local paths
+always stay this computer's own.
+
+This also happens automatically before each manifest backup.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		if _, err := os.Stat(args[0]); err == nil {
+			res, err := db.Merge(args[0])
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Merged %s\n", args[0])
+			printMergeResult(res)
+			return nil
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		var dest *config.Destination
+		for i := range cfg.Destinations {
+			if cfg.Destinations[i].Name == args[0] {
+				dest = &cfg.Destinations[i]
+			}
+		}
+		if dest == nil {
+			return fmt.Errorf("%q is neither a manifest file nor a configured destination", args[0])
+		}
+		machine, err := config.MachineID()
+		if err != nil {
+			return err
+		}
+
+		rc := rclone.NewClient()
+		merged, err := backup.MergeOthers(rc, db, []config.Destination{*dest}, machine)
+		for _, m := range merged {
+			fmt.Printf("Merged %s from %s\n", m.Version.Path, m.Dest)
+			printMergeResult(m.Result)
+		}
+		if err != nil {
+			return err
+		}
+		if len(merged) == 0 {
+			// Older versions kept a single shared backup.
+			if _, statErr := rc.Stat(dest.Remote(backup.LegacyPath)); statErr != nil {
+				fmt.Printf("No manifest backups from other computers on %s.\n", dest.Name)
+				return nil
+			}
+			res, err := backup.MergeFrom(rc, db, *dest, backup.LegacyPath)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Merged %s from %s\n", backup.LegacyPath, dest.Name)
+			printMergeResult(res)
+		}
+		return nil
+	},
+}
+
+var (
+	rebuildFrom    string
+	rebuildDevice  string
+	rebuildHash    bool
+	rebuildDryRun  bool
+	rebuildConfirm bool
+)
+
+var manifestRebuildCmd = &cobra.Command{
+	Use:   "rebuild --from <destination>",
+	Short: "Recreate manifest entries from files already on a destination",
+	Long: `For when the manifest is lost and there is no backup to restore. Lists
+the destination and matches what's there to the media on the connected
+headsets by file name and size, using the modification time preserved on
+upload to tell apart copies with the same name. Matched files are recorded
+as synced to that destination, so they aren't uploaded again and 'clean'
+can delete them from the headset.
+
+Another headset may have a file with the same name and size, so a copy
+only counts if its modification time or, with --hash, its SHA-256 matches
+too, and each copy is matched to one file at most, preferring the copy in
+the headset's own folder. The matches are listed first and nothing is
+recorded until you confirm. Run it once per destination.`,
+	PersistentPreRunE: requireDevices(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		var dest *config.Destination
+		for i := range cfg.Destinations {
+			if cfg.Destinations[i].Name == rebuildFrom {
+				dest = &cfg.Destinations[i]
+			}
+		}
+		if dest == nil {
+			return fmt.Errorf("destination %q not found", rebuildFrom)
+		}
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		adbClient := adb.NewClient()
+		var devices []adb.Device
+		if rebuildDevice != "" {
+			d, err := adbClient.FindDevice(rebuildDevice)
+			if err != nil {
+				return err
+			}
+			devices = []adb.Device{d}
+		} else {
+			all, err := adbClient.Devices()
+			if err != nil {
+				return err
+			}
+			for _, d := range adb.UniqueDevices(all) {
+				if d.IsOnline() {
+					devices = append(devices, d)
+				}
+			}
+		}
+		if len(devices) == 0 {
+			fmt.Println("No connected devices found.")
+			return nil
+		}
+
+		fmt.Printf("Listing %s...\n", dest.RcloneRemote)
+		plan, err := qsync.PlanRebuild(adbClient, rclone.NewClient(), db, cfg, *dest, devices, rebuildHash)
+		if err != nil {
+			return err
+		}
+
+		for _, m := range plan.Matches {
+			how := "name, size, mtime"
+			if !m.ByMTime {
+				how = "name, size"
+			}
+			if m.SHA256 != "" {
+				how += ", SHA-256"
+			}
+			fmt.Printf("  %s -> %s (%s)\n", m.File.Path, m.Object.Path, how)
+		}
+		for _, f := range plan.Ambiguous {
+			fmt.Printf("  %s: several copies match, skipped\n", f.Path)
+		}
+		for _, f := range plan.Mismatched {
+			fmt.Printf("  %s: a copy has the same name and size but a different hash, skipped\n", f.Path)
+		}
+		for _, m := range plan.Unconfirmed {
+			fmt.Printf("  %s: %s has the same name and size, but nothing else confirms it's this file, skipped\n",
+				m.File.Path, m.Object.Path)
+		}
+		for _, e := range plan.Errors {
+			fmt.Fprintf(os.Stderr, "  Error: %s\n", e)
+		}
+		fmt.Printf("\n%d matched, %d already recorded, %d ambiguous, %d unconfirmed, %d not on %s\n",
+			len(plan.Matches), plan.Known, len(plan.Ambiguous)+len(plan.Mismatched), len(plan.Unconfirmed),
+			plan.Unmatched, dest.Name)
+		if len(plan.Unconfirmed) > 0 && !rebuildHash {
+			fmt.Println("Unconfirmed files may be matched with --hash, where the destination stores SHA-256.")
+		}
+
+		if len(plan.Matches) == 0 {
+			return nil
+		}
+		if rebuildDryRun {
+			fmt.Println("(dry run — nothing recorded)")
+			return nil
+		}
+		if !rebuildConfirm {
+			fmt.Printf("\nRecord these %d files as synced to %s? [y/N] ", len(plan.Matches), dest.Name)
+			reader := bufio.NewReader(os.Stdin)
+			answer, _ := reader.ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			if answer != "y" && answer != "yes" {
+				fmt.Println("Aborted.")
+				return nil
+			}
+		}
+		n, err := plan.Apply(db)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Recorded %d files as synced to %s.\n", n, dest.Name)
+		return nil
+	},
+}
+
+func printMergeResult(r manifest.MergeResult) {
+	if !r.Changed() {
+		fmt.Println("  Already up to date.")
+		return
+	}
+	fmt.Printf("  Files: %d added, %d updated\n", r.FilesAdded, r.FilesUpdated)
+	fmt.Printf("  Syncs: %d added, %d updated\n", r.SyncsAdded, r.SyncsUpdated)
+	if r.Labels > 0 {
+		fmt.Printf("  App labels: %d\n", r.Labels)
+	}
+}
+
+var probeAll bool
+
+var manifestProbeCmd = &cobra.Command{
+	Use:   "probe",
+	Short: "Read capture time, duration and resolution from local copies",
+	Long: `Reads capture metadata (recording start time and duration, resolution and
+codec of videos, size of screenshots) from the local copies of files pulled
+before FetchQuest recorded it. New pulls are read automatically.
+
+Layouts that use {year}, {month} or {day} go by the capture time where it
+is known, so files may then belong in another folder: run
+'fetchquest relayout' to move them.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		res, err := qsync.BackfillMedia(db, probeAll)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Read metadata from %d files\n", res.Probed)
+		if res.Empty > 0 {
+			fmt.Printf("  %d had none that could be read\n", res.Empty)
+		}
+		if res.NoLocal > 0 {
+			fmt.Printf("  %d have no local copy to read\n", res.NoLocal)
+		}
+		for _, e := range res.Errors {
+			fmt.Fprintf(os.Stderr, "  Error: %s\n", e)
+		}
+		return nil
+	},
+}
+
+func init() {
+	manifestCmd.AddCommand(manifestMergeCmd)
+	manifestRebuildCmd.Flags().StringVar(&rebuildFrom, "from", "", "Destination to rebuild from (required)")
+	manifestRebuildCmd.MarkFlagRequired("from")
+	manifestRebuildCmd.Flags().StringVarP(&rebuildDevice, "device", "d", "", "Device serial (default: all)")
+	manifestRebuildCmd.Flags().BoolVar(&rebuildHash, "hash", false, "Also compare SHA-256 where the destination stores it (slower)")
+	manifestRebuildCmd.Flags().BoolVar(&rebuildDryRun, "dry-run", false, "Show the matches without recording them")
+	manifestRebuildCmd.Flags().BoolVar(&rebuildConfirm, "confirm", false, "Skip confirmation prompt")
+	manifestCmd.AddCommand(manifestRebuildCmd)
+	manifestProbeCmd.Flags().BoolVar(&probeAll, "all", false, "Read files that already have metadata again")
+	manifestCmd.AddCommand(manifestProbeCmd)
+	rootCmd.AddCommand(manifestCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+
+	"github.com/spf13/cobra"
+)
+
+var pairConnect string
+
+var pairCmd = &cobra.Command{
+	Use:   "pair <host:port> <code>",
+	Short: "Pair with a Quest over wireless debugging, no USB cable needed",
+	Long: `Pairs with a headset that has wireless debugging turned on (Android 11+
+style pairing). On the headset, open Developer settings > Wireless debugging >
+Pair device with pairing code, then pass the IP address:port and six-digit
+code it shows.
+
+The address on the main Wireless debugging screen is a different port, used
+for connecting. Pass it with --connect so later runs of sync, pull, clean and
+devices can reconnect to it; without it FetchQuest relies on the ADB server
+finding the headset on the network by itself.
+
+Example: fetchquest pair 192.168.1.42:37123 482913 --connect 192.168.1.42:41025`,
+	Args:              cobra.ExactArgs(2),
+	PersistentPreRunE: requireDeps(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pairAddr, code := args[0], args[1]
+		if _, _, err := net.SplitHostPort(pairAddr); err != nil {
+			return fmt.Errorf("invalid pairing address %q: expected host:port", pairAddr)
+		}
+		var host string
+		var port int
+		if pairConnect != "" {
+			h, p, err := net.SplitHostPort(pairConnect)
+			if err != nil {
+				return fmt.Errorf("invalid --connect address %q: expected host:port", pairConnect)
+			}
+			host = h
+			port, err = strconv.Atoi(p)
+			if err != nil {
+				return fmt.Errorf("invalid --connect port %q", p)
+			}
+		}
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("Pairing with %s... ", pairAddr)
+		client := adb.NewClient()
+		d, err := client.PairDevice(pairAddr, code, pairConnect)
+		if err != nil {
+			fmt.Println("failed")
+			return err
+		}
+		fmt.Println("ok")
+
+		dc := cfg.Devices[d.ID]
+		dc.PairedAddr = pairAddr
+		if host != "" {
+			dc.WiFiIP = host
+			dc.WiFiPort = port
+		}
+		cfg.Devices[d.ID] = dc
+		if err := config.Save(cfg); err != nil {
+			return err
+		}
+
+		model := d.Model
+		if model == "" {
+			model = "unknown model"
+		}
+		fmt.Printf("Connected to %s (%s) as %s\n", d.ID, model, d.Serial)
+		if host == "" {
+			fmt.Println("Tip: pass --connect <ip:port> from the Wireless debugging screen so FetchQuest can reconnect on its own.")
+		}
+		checkNewDevices()
+		return nil
+	},
+}
+
+func init() {
+	pairCmd.Flags().StringVar(&pairConnect, "connect", "", "Wireless debugging address to connect to after pairing (ip:port)")
+	rootCmd.AddCommand(pairCmd)
+}
@@ -12,12 +12,15 @@ import (
 	"github.com/spf13/cobra"
 )
 
-var pullDevice string
+var (
+	pullDevice string
+	pullForce  bool
+)
 
 var pullCmd = &cobra.Command{
 	Use:               "pull",
 	Short:             "Pull media from Quest(s) to local sync directory",
-	PersistentPreRunE: requireDeps(),
+	PersistentPreRunE: requireDevices(),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
@@ -29,15 +32,21 @@ var pullCmd = &cobra.Command{
 		}
 		defer db.Close()
 
+		adbClient := adb.NewClient()
 		puller := &sync.Puller{
-			ADB:      adb.NewClient(),
+			ADB:      adbClient,
 			Manifest: db,
 			Config:   cfg,
+			Power:    powerGuard(adbClient, cfg, pullForce, true),
 		}
 
 		if pullDevice != "" {
-			fmt.Printf("Pulling media from device %s...\n", pullDevice)
-			result, err := puller.PullDevice(pullDevice)
+			d, err := adbClient.FindDevice(pullDevice)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Pulling media from device %s...\n", d.ID)
+			result, err := puller.PullDevice(d)
 			if err != nil {
 				return err
 			}
@@ -71,5 +80,6 @@ func printPullResult(r sync.PullResult) {
 
 func init() {
 	pullCmd.Flags().StringVarP(&pullDevice, "device", "d", "", "Device serial to pull from (default: all)")
+	pullCmd.Flags().BoolVar(&pullForce, "force", false, "Pull even if the headset's battery is low")
 	rootCmd.AddCommand(pullCmd)
 }
@@ -0,0 +1,157 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	relayoutDest    string
+	relayoutDryRun  bool
+	relayoutConfirm bool
+)
+
+var relayoutCmd = &cobra.Command{
+	Use:     "relayout",
+	Aliases: []string{"migrate-layout"},
+	Short:   "Move already-synced files to where the current layout puts them",
+	Long: `Moves files already synced into the current layout, both in the sync
+directory and on every destination, and updates the manifest. Run it after
+changing the layout with 'fetchquest config set-layout', or after upgrading
+from a version that kept all headsets' files together in
+<sync_dir>/Videos/... With --dest, only the copies on that destination move.
+
+Files on destinations are moved with 'rclone moveto', which is done
+server-side where the backend supports it, so nothing is uploaded again.
+
+Where two headsets' files already overwrote each other, the one that was
+lost is forgotten: it will be pulled again if it's still on the headset.
+
+The moves are saved in the manifest before any are made. If a relayout is
+interrupted, running 'fetchquest relayout' again finishes it first.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if relayoutDest != "" {
+			found := false
+			for _, d := range cfg.Destinations {
+				found = found || d.Name == relayoutDest
+			}
+			if !found {
+				return fmt.Errorf("destination %q not found", relayoutDest)
+			}
+		}
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+		rc := rclone.NewClient()
+
+		pending, err := db.PendingRelayout()
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			fmt.Printf("An earlier relayout was interrupted (%d to go).\n", len(pending))
+			if relayoutDryRun {
+				for _, mv := range pending {
+					if mv.Local() && mv.To == "" {
+						fmt.Printf("  forget %s\n", mv.From)
+					} else if mv.Local() {
+						fmt.Printf("  %s -> %s\n", mv.From, mv.To)
+					} else {
+						fmt.Printf("  %s: %s -> %s\n", mv.Destination, mv.From, mv.To)
+					}
+				}
+				fmt.Println("(dry run — nothing moved)")
+				return nil
+			}
+			fmt.Println("Finishing it first...")
+			// Moves that fail are planned again below.
+			relayoutDone(qsync.ResumeRelayout(rc, db, cfg))
+			fmt.Println()
+		}
+
+		plan, err := qsync.PlanLayout(db, cfg)
+		if err != nil {
+			return err
+		}
+		if relayoutDest != "" {
+			plan = plan.ForDest(relayoutDest)
+		}
+		if plan.Empty() {
+			fmt.Println("Everything is already where the layout puts it.")
+			return nil
+		}
+
+		local, remote := 0, 0
+		for _, mv := range plan.Moves {
+			if mv.To == "" && mv.From != "" {
+				fmt.Printf("  %s on %s was overwritten by another headset's file — its local copy will be forgotten\n", mv.Entry.RemotePath, mv.Entry.DeviceSerial)
+			} else if mv.From != mv.To {
+				local++
+				fmt.Printf("  %s -> %s\n", mv.From, mv.To)
+			}
+			for _, r := range mv.Remote {
+				remote++
+				fmt.Printf("  %s -> %s\n", r.Dest.Remote(r.From), r.Dest.Remote(r.To))
+			}
+		}
+		for _, e := range plan.Lost {
+			fmt.Printf("  %s on %s was overwritten by another headset's file — will be pulled again\n", e.RemotePath, e.DeviceSerial)
+		}
+		for _, se := range plan.Requeued {
+			fmt.Printf("  %s on %s was overwritten by another headset's file — will be uploaded again\n", se.RemotePath, se.Sync.Destination)
+		}
+		fmt.Printf("\n%d local and %d remote files to move, %d to pull again, %d to upload again\n",
+			local, remote, len(plan.Lost), len(plan.Requeued))
+
+		if relayoutDryRun {
+			fmt.Println("(dry run — nothing moved)")
+			return nil
+		}
+		if !relayoutConfirm {
+			fmt.Print("\nMove them? [y/N] ")
+			reader := bufio.NewReader(os.Stdin)
+			answer, _ := reader.ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			if answer != "y" && answer != "yes" {
+				fmt.Println("Aborted.")
+				return nil
+			}
+		}
+
+		return relayoutDone(qsync.Relayout(rc, db, cfg, plan))
+	},
+}
+
+// relayoutDone prints the outcome of a relayout.
+func relayoutDone(result qsync.LayoutResult) error {
+	fmt.Printf("\nMoved %d local and %d remote files.\n", result.LocalMoved, result.RemoteMoved)
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "  Error: %s\n", e)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d files could not be moved — run 'fetchquest relayout' again to retry", len(result.Errors))
+	}
+	return nil
+}
+
+func init() {
+	relayoutCmd.Flags().StringVar(&relayoutDest, "dest", "", "Only move files on this destination")
+	relayoutCmd.Flags().BoolVar(&relayoutDryRun, "dry-run", false, "Show what would move without moving anything")
+	relayoutCmd.Flags().BoolVar(&relayoutConfirm, "confirm", false, "Skip confirmation prompt")
+	rootCmd.AddCommand(relayoutCmd)
+}
@@ -30,6 +30,19 @@ func requireDeps() func(cmd *cobra.Command, args []string) error {
 	}
 }
 
+// requireDevices is like requireDeps, but first reconnects any headsets
+// configured for wireless ADB so they are included in the device scan.
+func requireDevices() func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		if err := checkDeps(); err != nil {
+			return err
+		}
+		connectWiFiDevices()
+		checkNewDevices()
+		return nil
+	}
+}
+
 // Execute runs the root command.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
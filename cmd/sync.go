@@ -1,8 +1,10 @@
 package cmd
 
 import (
+	"bufio"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/FluidXR/fetchquest/internal/adb"
 	"github.com/FluidXR/fetchquest/internal/config"
@@ -16,12 +18,14 @@ import (
 var (
 	syncDevice    string
 	syncSkipLocal bool
+	syncForce     bool
+	syncApps      []string
 )
 
 var syncCmd = &cobra.Command{
 	Use:               "sync",
 	Short:             "Pull media from Quest(s) then push to destination(s)",
-	PersistentPreRunE: requireDeps(),
+	PersistentPreRunE: requireDevices(),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cfg, err := config.Load()
 		if err != nil {
@@ -36,21 +40,37 @@ var syncCmd = &cobra.Command{
 		}
 		defer db.Close()
 
+		apps, err := qsync.NewAppFilter(db, syncApps)
+		if err != nil {
+			return err
+		}
+
 		rc := rclone.NewClient()
+		adbClient := adb.NewClient()
+
+		var device adb.Device
+		if syncDevice != "" {
+			device, err = adbClient.FindDevice(syncDevice)
+			if err != nil {
+				return err
+			}
+		}
 
 		if syncSkipLocal {
 			// Stream mode: pull and sync one file at a time, no local copies
 			streamer := &qsync.Streamer{
-				ADB:       adb.NewClient(),
+				ADB:       adbClient,
 				Rclone:    rc,
 				Manifest:  db,
 				Config:    cfg,
 				SkipLocal: true,
+				Power:     powerGuard(adbClient, cfg, syncForce, true),
+				Apps:      apps,
 			}
 
 			if syncDevice != "" {
-				fmt.Printf("Syncing media from device %s (skip-local)...\n", syncDevice)
-				result, err := streamer.StreamDevice(syncDevice)
+				fmt.Printf("Syncing media from device %s (skip-local)...\n", device.ID)
+				result, err := streamer.StreamDevice(device)
 				if err != nil {
 					return err
 				}
@@ -71,14 +91,16 @@ var syncCmd = &cobra.Command{
 		} else {
 			// Normal mode: pull all, then push all
 			puller := &qsync.Puller{
-				ADB:      adb.NewClient(),
+				ADB:      adbClient,
 				Manifest: db,
 				Config:   cfg,
+				Power:    powerGuard(adbClient, cfg, syncForce, true),
+				Apps:     apps,
 			}
 
 			fmt.Println("=== Pull Phase ===")
 			if syncDevice != "" {
-				result, err := puller.PullDevice(syncDevice)
+				result, err := puller.PullDevice(device)
 				if err != nil {
 					return err
 				}
@@ -100,6 +122,7 @@ var syncCmd = &cobra.Command{
 				Rclone:   rc,
 				Manifest: db,
 				Config:   cfg,
+				Apps:     apps,
 			}
 
 			fmt.Println("\n=== Push Phase ===")
@@ -122,6 +145,25 @@ var syncCmd = &cobra.Command{
 	},
 }
 
+// powerGuard builds the battery check used before syncing a headset. With
+// force the check is skipped; otherwise a low battery is refused, or asked
+// about when interactive.
+func powerGuard(adbClient *adb.Client, cfg *config.Config, force, interactive bool) *qsync.PowerGuard {
+	g := &qsync.PowerGuard{ADB: adbClient, MinBattery: cfg.MinBattery}
+	if force {
+		g.MinBattery = 0
+	} else if interactive {
+		g.Confirm = func(d adb.Device, b adb.Battery) bool {
+			fmt.Printf("%s battery is at %d%% and not charging. Sync anyway? [y/N] ", d.ID, b.Level)
+			reader := bufio.NewReader(os.Stdin)
+			answer, _ := reader.ReadString('\n')
+			answer = strings.TrimSpace(strings.ToLower(answer))
+			return answer == "y" || answer == "yes"
+		}
+	}
+	return g
+}
+
 func printStreamResult(r qsync.StreamResult) {
 	fmt.Printf("\nDevice: %s\n", r.DeviceSerial)
 	fmt.Printf("  Synced: %d files\n", r.FilesStreamed)
@@ -134,5 +176,7 @@ func printStreamResult(r qsync.StreamResult) {
 func init() {
 	syncCmd.Flags().StringVarP(&syncDevice, "device", "d", "", "Device serial (default: all)")
 	syncCmd.Flags().BoolVar(&syncSkipLocal, "skip-local", false, "Don't keep local copies — sync straight to destinations")
+	syncCmd.Flags().StringSliceVar(&syncApps, "app", nil, "Only sync captures from this app (name or package; repeatable)")
+	syncCmd.Flags().BoolVar(&syncForce, "force", false, "Sync even if the headset's battery is low")
 	rootCmd.AddCommand(syncCmd)
 }
@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	verifyDest   string
+	verifyHash   bool
+	verifyRepair bool
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Check destinations against the manifest",
+	Long: `Lists each destination and compares it with what the manifest says was
+synced there. Reports files that are missing, have the wrong size (or hash,
+with --hash), and files on the destination the manifest doesn't know about.
+
+With --repair, missing and damaged files are forgotten for that destination
+so the next push uploads them again, and intact copies that were never
+verified are marked as verified.`,
+	PersistentPreRunE: requireDeps(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if len(cfg.Destinations) == 0 {
+			return fmt.Errorf("no destinations configured — run 'fetchquest config add-dest' first")
+		}
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		dests := cfg.Destinations
+		if verifyDest != "" {
+			dests = nil
+			for _, d := range cfg.Destinations {
+				if d.Name == verifyDest {
+					dests = append(dests, d)
+				}
+			}
+			if len(dests) == 0 {
+				return fmt.Errorf("destination %q not found", verifyDest)
+			}
+		}
+
+		rc := rclone.NewClient()
+		problems := 0
+		for _, dest := range dests {
+			fmt.Printf("\nDestination: %s\n", dest.Name)
+			audit, err := qsync.AuditDest(rc, db, cfg, dest, verifyHash)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "  Error: %v\n", err)
+				problems++
+				continue
+			}
+			fmt.Printf("  OK: %d files\n", len(audit.OK))
+			if len(audit.Missing) > 0 {
+				fmt.Printf("  Missing: %d files\n", len(audit.Missing))
+				for _, e := range audit.Missing {
+					fmt.Printf("    %s (%s)\n", e.RemotePath, e.DeviceSerial)
+				}
+			}
+			if len(audit.Damaged) > 0 {
+				fmt.Printf("  Damaged: %d files\n", len(audit.Damaged))
+				for _, d := range audit.Damaged {
+					fmt.Printf("    %s: %s\n", d.Object.Path, d.Reason)
+				}
+			}
+			if len(audit.Unknown) > 0 {
+				fmt.Printf("  Not in manifest: %d files\n", len(audit.Unknown))
+				for _, o := range audit.Unknown {
+					fmt.Printf("    %s\n", o.Path)
+				}
+			}
+			bad := len(audit.Missing) + len(audit.Damaged)
+			problems += bad
+
+			if !verifyRepair {
+				continue
+			}
+			queued, err := audit.Repair(db)
+			if err != nil {
+				return err
+			}
+			if queued > 0 {
+				fmt.Printf("  Queued %d files to upload again\n", queued)
+			}
+			noLocal := 0
+			for _, e := range audit.Missing {
+				if e.LocalPath == "" {
+					noLocal++
+				}
+			}
+			for _, d := range audit.Damaged {
+				if d.LocalPath == "" {
+					noLocal++
+				}
+			}
+			if noLocal > 0 {
+				fmt.Printf("  %d of them have no local copy, so they can't be re-uploaded from this computer\n", noLocal)
+			}
+		}
+
+		if problems > 0 && !verifyRepair {
+			fmt.Println("\nRun 'fetchquest verify --repair' to re-upload missing and damaged files on the next push.")
+		}
+		return nil
+	},
+}
+
+func init() {
+	verifyCmd.Flags().StringVar(&verifyDest, "dest", "", "Only check this destination (default: all)")
+	verifyCmd.Flags().BoolVar(&verifyHash, "hash", false, "Also compare SHA-256 where the destination can report it (slow on local/SMB)")
+	verifyCmd.Flags().BoolVar(&verifyRepair, "repair", false, "Forget missing/damaged files so they are pushed again")
+	rootCmd.AddCommand(verifyCmd)
+}
@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	watchSkipLocal bool
+	watchDebounce  time.Duration
+	watchForce     bool
+)
+
+// wifiRetryInterval is how often watch retries `adb connect` for headsets
+// configured for wireless ADB, so they are picked up when they come in range.
+const wifiRetryInterval = 30 * time.Second
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Sync automatically whenever a known Quest is connected",
+	Long: `Stays running and watches for headsets. When a known Quest comes online
+(plugged in, or reachable over WiFi) it is synced just like 'fetchquest sync
+--device <serial>'. Headsets that have never been seen before are skipped
+until they've been given a nickname with 'fetchquest devices'. Headsets below
+the configured battery level are skipped unless they are charging (or --force).
+
+Press Ctrl+C to stop.`,
+	PersistentPreRunE: requireDevices(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := config.Load()
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		if len(cfg.Destinations) == 0 {
+			return fmt.Errorf("no destinations configured — run 'fetchquest config add-dest' first")
+		}
+		db, err := manifest.Open(config.ConfigDir())
+		if err != nil {
+			return fmt.Errorf("open manifest: %w", err)
+		}
+		defer db.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		adbClient := adb.NewClient()
+		rc := rclone.NewClient()
+
+		go func() {
+			ticker := time.NewTicker(wifiRetryInterval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if cfg, err := config.Load(); err == nil {
+						adbClient.ConnectAll(cfg.WiFiAddrs())
+					}
+				}
+			}
+		}()
+
+		w := &qsync.Watcher{
+			ADB:      adbClient,
+			Debounce: watchDebounce,
+			Known: func(d adb.Device) bool {
+				cfg, err := config.Load()
+				if err != nil {
+					return false
+				}
+				if _, ok := cfg.Devices[d.ID]; ok {
+					return true
+				}
+				fmt.Printf("New device %s connected — run 'fetchquest devices' to add it, then reconnect.\n", d.ID)
+				return false
+			},
+			OnConnect: func(ctx context.Context, d adb.Device) {
+				// Pick up config changes made while watching.
+				cfg, err := config.Load()
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error: load config: %v\n", err)
+					return
+				}
+				name := d.ID
+				if dc := cfg.Devices[d.ID]; dc.Nickname != "" {
+					name = fmt.Sprintf("%s (%s)", dc.Nickname, d.ID)
+				}
+				fmt.Printf("\n[%s] %s connected, syncing...\n", time.Now().Format("15:04:05"), name)
+				syncOneDevice(db, cfg, adbClient, rc, d, watchSkipLocal, watchForce)
+				fmt.Printf("[%s] Done with %s. Watching for devices...\n", time.Now().Format("15:04:05"), name)
+			},
+		}
+
+		fmt.Println("Watching for devices... (Ctrl+C to stop)")
+		if err := w.Run(ctx); err != nil && ctx.Err() == nil {
+			return err
+		}
+		fmt.Println("\nStopped.")
+		return nil
+	},
+}
+
+// syncOneDevice runs the same pull/push pipeline as `fetchquest sync`
+// for a single device, printing results as it goes.
+func syncOneDevice(db *manifest.DB, cfg *config.Config, adbClient *adb.Client, rc *rclone.Client, d adb.Device, skipLocal, force bool) {
+	if skipLocal {
+		streamer := &qsync.Streamer{
+			ADB:       adbClient,
+			Rclone:    rc,
+			Manifest:  db,
+			Config:    cfg,
+			SkipLocal: true,
+			Power:     powerGuard(adbClient, cfg, force, false),
+		}
+		result, err := streamer.StreamDevice(d)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Error: %v\n", err)
+			return
+		}
+		printStreamResult(result)
+	} else {
+		puller := &qsync.Puller{
+			ADB:      adbClient,
+			Manifest: db,
+			Config:   cfg,
+			Power:    powerGuard(adbClient, cfg, force, false),
+		}
+		result, err := puller.PullDevice(d)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Error: %v\n", err)
+			return
+		}
+		printPullResult(result)
+
+		pusher := &qsync.Pusher{
+			Rclone:   rc,
+			Manifest: db,
+			Config:   cfg,
+		}
+		pushResults, err := pusher.PushAll()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "  Error: %v\n", err)
+			return
+		}
+		for _, r := range pushResults {
+			fmt.Printf("\nDestination: %s\n", r.Destination)
+			fmt.Printf("  Pushed: %d files\n", r.FilesPushed)
+			fmt.Printf("  Skipped: %d files\n", r.FilesSkipped)
+			for _, e := range r.Errors {
+				fmt.Fprintf(os.Stderr, "  Error: %s\n", e)
+			}
+		}
+	}
+	backupManifest(db, cfg, rc)
+}
+
+func init() {
+	watchCmd.Flags().BoolVar(&watchSkipLocal, "skip-local", false, "Don't keep local copies — sync straight to destinations")
+	watchCmd.Flags().DurationVar(&watchDebounce, "settle", qsync.DefaultDebounce, "How long a device must stay connected before syncing")
+	watchCmd.Flags().BoolVar(&watchForce, "force", false, "Sync even if a headset's battery is low")
+	rootCmd.AddCommand(watchCmd)
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/discovery"
+
+	"github.com/spf13/cobra"
+)
+
+var wifiCmd = &cobra.Command{
+	Use:   "wifi",
+	Short: "Set up wireless ADB for your Quests",
+}
+
+var wifiEnableCmd = &cobra.Command{
+	Use:   "enable <serial>",
+	Short: "Switch a USB-connected Quest to wireless ADB and remember its IP",
+	Long: `Puts the headset's ADB daemon into TCP mode (adb tcpip 5555), reads the
+headset's WiFi address and saves it to the config. Later runs of sync, pull,
+clean and devices reconnect to it automatically, so the cable can stay unplugged.
+
+The headset must be connected over USB and on the same network as this computer.
+Wireless ADB is turned off again when the headset reboots.`,
+	Args:              cobra.ExactArgs(1),
+	PersistentPreRunE: requireDeps(),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		serial := args[0]
+
+		cfg, err := config.Load()
+		if err != nil {
+			return err
+		}
+
+		client := adb.NewClient()
+		d, err := client.FindDevice(serial)
+		if err != nil {
+			return fmt.Errorf("device %s is not connected — plug it in via USB first", serial)
+		}
+
+		// Read the address first: adbd drops the USB connection while it restarts.
+		ip, err := client.WiFiIP(d.Serial)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Headset WiFi address: %s\n", ip)
+
+		if err := client.TCPIP(d.Serial, adb.DefaultWiFiPort); err != nil {
+			return err
+		}
+
+		dc := cfg.Devices[d.ID]
+		dc.WiFiIP = ip
+		dc.WiFiPort = 0 // adb tcpip always listens on the default port
+		cfg.Devices[d.ID] = dc
+		if err := config.Save(cfg); err != nil {
+			return err
+		}
+		fmt.Printf("Saved WiFi IP for %s\n", d.ID)
+
+		fmt.Print("Connecting over WiFi... ")
+		for attempt := 0; attempt < 5; attempt++ {
+			time.Sleep(time.Second)
+			if err = client.Connect(ip, adb.DefaultWiFiPort); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			fmt.Println("failed")
+			return fmt.Errorf("headset switched to wireless ADB but connecting failed: %w", err)
+		}
+		fmt.Println("ok")
+		fmt.Println("You can unplug the USB cable now.")
+		return nil
+	},
+}
+
+// connectWiFiDevices runs `adb connect` for every configured WiFi IP that
+// isn't already attached, looking headsets up on the network if their
+// address changed. Failures are reported but never fatal — a headset on the
+// shelf may simply be switched off.
+func connectWiFiDevices() {
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+	addrs := cfg.WiFiAddrs()
+	if len(addrs) == 0 {
+		return
+	}
+	errs, changed := discovery.ConnectConfigured(context.Background(), adb.NewClient(), cfg)
+	if changed {
+		if err := config.Save(cfg); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not save config: %v\n", err)
+		}
+	}
+	for addr, err := range errs {
+		fmt.Fprintf(os.Stderr, "Could not connect to %s over WiFi: %v\n", addr, err)
+	}
+}
+
+func init() {
+	wifiCmd.AddCommand(wifiEnableCmd)
+	rootCmd.AddCommand(wifiCmd)
+}
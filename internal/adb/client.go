@@ -3,12 +3,15 @@ package adb
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -19,9 +22,17 @@ type FileInfo struct {
 	MTime time.Time
 }
 
-// Client wraps ADB command-line calls.
+// Client talks to the ADB server. It speaks the server's wire protocol
+// directly (host:devices-l, host:transport, sync:, shell:) and falls back
+// to running the adb binary when the server socket can't be reached.
 type Client struct {
-	bin string // path to adb binary
+	bin  string // path to adb binary; used to start the server and as a fallback
+	addr string // ADB server address (host:port)
+
+	startOnce sync.Once
+	mu        sync.Mutex                 // guards the per-transport caches below
+	featCache map[string]map[string]bool // transport serial -> device features
+	idCache   map[string]string          // transport serial -> ro.serialno
 }
 
 // NewClient creates a new ADB client. If adbPath is empty, "adb" is used (found via PATH).
@@ -32,7 +43,23 @@ func NewClient(adbPath ...string) *Client {
 	} else {
 		bin = findBin("adb")
 	}
-	return &Client{bin: bin}
+	return &Client{
+		bin:       bin,
+		addr:      defaultServerAddr(),
+		featCache: make(map[string]map[string]bool),
+		idCache:   make(map[string]string),
+	}
+}
+
+// NewServerClient creates a client that only speaks the wire protocol to the
+// ADB server at addr, with no adb binary to fall back on. This is mainly
+// useful for pointing the client at a fake server.
+func NewServerClient(addr string) *Client {
+	return &Client{
+		addr:      addr,
+		featCache: make(map[string]map[string]bool),
+		idCache:   make(map[string]string),
+	}
 }
 
 // findBin returns the full path to a binary, checking PATH first then
@@ -57,23 +84,86 @@ func findBin(name string) string {
 // Bin returns the adb binary path.
 func (c *Client) Bin() string { return c.bin }
 
+// fallback reports whether err means the server is unreachable and the
+// adb binary should be used instead.
+func (c *Client) fallback(err error) bool {
+	return c.bin != "" && errors.Is(err, ErrServerUnavailable)
+}
+
 // Devices returns all connected ADB devices.
 func (c *Client) Devices() ([]Device, error) {
-	out, err := newCmd(c.bin, "devices", "-l").CombinedOutput()
+	var devs []Device
+	out, err := c.hostQuery(context.Background(), "host:devices-l")
+	if c.fallback(err) {
+		devs, err = c.execDevices()
+	} else if err == nil {
+		devs = parseDeviceList(out)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("adb devices: %w\n%s", err, out)
+		return nil, err
+	}
+	c.identify(devs)
+	return devs, nil
+}
+
+// identify fills in Device.ID from ro.serialno. Over WiFi the transport
+// serial is only ip:port, so this is what ties both transports to one headset.
+// Results are cached per transport serial for as long as it stays attached.
+func (c *Client) identify(devs []Device) {
+	present := make(map[string]bool)
+	for i := range devs {
+		d := &devs[i]
+		present[d.Serial] = true
+		c.mu.Lock()
+		id, ok := c.idCache[d.Serial]
+		c.mu.Unlock()
+		if ok {
+			d.ID = id
+			continue
+		}
+		d.ID = d.Serial
+		if !d.IsOnline() {
+			continue
+		}
+		out, err := c.Shell(d.Serial, "getprop ro.serialno")
+		if id := strings.TrimSpace(out); err == nil && id != "" {
+			d.ID = id
+			c.mu.Lock()
+			c.idCache[d.Serial] = id
+			c.mu.Unlock()
+		}
+	}
+
+	// A transport serial that went away may come back as a different
+	// headset (DHCP hands the same IP to someone else), so forget it.
+	c.mu.Lock()
+	for serial := range c.idCache {
+		if !present[serial] {
+			delete(c.idCache, serial)
+		}
 	}
-	return parseDeviceList(string(out)), nil
+	for serial := range c.featCache {
+		if !present[serial] {
+			delete(c.featCache, serial)
+		}
+	}
+	c.mu.Unlock()
 }
 
 // Connect connects to a wireless ADB device.
 func (c *Client) Connect(ip string, port int) error {
-	addr := fmt.Sprintf("%s:%d", ip, port)
-	out, err := newCmd(c.bin, "connect", addr).CombinedOutput()
+	return c.connect(context.Background(), fmt.Sprintf("%s:%d", ip, port))
+}
+
+func (c *Client) connect(ctx context.Context, addr string) error {
+	output, err := c.hostQuery(ctx, "host:connect:"+addr)
+	if c.fallback(err) {
+		output, err = c.execConnect(addr)
+	}
 	if err != nil {
-		return fmt.Errorf("adb connect %s: %w\n%s", addr, err, out)
+		return err
 	}
-	output := string(out)
+	// The server answers OKAY either way; the message says whether it worked.
 	if strings.Contains(output, "connected") {
 		return nil
 	}
@@ -82,18 +172,7 @@ func (c *Client) Connect(ip string, port int) error {
 
 // ListFiles lists files in a directory on the device, non-recursively.
 func (c *Client) ListFiles(serial, remotePath string) ([]FileInfo, error) {
-	// Use `ls -la` to get file info
-	out, err := newCmd(c.bin, "-s", serial, "shell",
-		fmt.Sprintf("find %s -maxdepth 1 -type f -exec stat -c '%%s %%Y %%n' {} +", remotePath),
-	).CombinedOutput()
-	if err != nil {
-		// Directory might not exist
-		if strings.Contains(string(out), "No such file") {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("adb shell find %s: %w\n%s", remotePath, err, out)
-	}
-	return parseStatOutput(string(out)), nil
+	return c.listFiles(context.Background(), serial, remotePath, false)
 }
 
 // ListFilesRecursive lists all files recursively under a directory.
@@ -103,35 +182,111 @@ func (c *Client) ListFilesRecursive(serial, remotePath string) ([]FileInfo, erro
 
 // ListFilesRecursiveCtx is like ListFilesRecursive but accepts a context for cancellation.
 func (c *Client) ListFilesRecursiveCtx(ctx context.Context, serial, remotePath string) ([]FileInfo, error) {
-	out, err := newCmdContext(ctx, c.bin, "-s", serial, "shell",
-		fmt.Sprintf("find %s -type f -exec stat -c '%%s %%Y %%n' {} +", remotePath),
-	).CombinedOutput()
+	return c.listFiles(ctx, serial, remotePath, true)
+}
+
+func (c *Client) listFiles(ctx context.Context, serial, remotePath string, recursive bool) ([]FileInfo, error) {
+	s, err := c.openSync(ctx, serial)
+	if c.fallback(err) {
+		return c.execListFiles(ctx, serial, remotePath, recursive)
+	}
 	if err != nil {
-		if ctx.Err() != nil {
-			return nil, ctx.Err()
-		}
-		if strings.Contains(string(out), "No such file") {
-			return nil, nil
+		return nil, err
+	}
+	defer s.Close()
+
+	var files []FileInfo
+	dirs := []string{strings.TrimRight(remotePath, "/")}
+	for len(dirs) > 0 {
+		dir := dirs[0]
+		dirs = dirs[1:]
+		err := s.list(dir+"/", func(e syncEntry) {
+			p := path.Join(dir, e.name)
+			switch {
+			case e.isDir() && recursive:
+				dirs = append(dirs, p)
+			case e.isRegular():
+				files = append(files, FileInfo{Path: p, Size: e.size, MTime: e.mtime})
+			}
+		})
+		if err != nil {
+			return nil, c.ctxErr(ctx, fmt.Errorf("adb list %s: %w", dir, err))
 		}
-		return nil, fmt.Errorf("adb shell find %s: %w\n%s", remotePath, err, out)
 	}
-	return parseStatOutput(string(out)), nil
+	return files, nil
+}
+
+// Stat returns information about a single file on the device.
+// A missing file yields an error that satisfies errors.Is(err, fs.ErrNotExist).
+func (c *Client) Stat(serial, remotePath string) (FileInfo, error) {
+	ctx := context.Background()
+	s, err := c.openSync(ctx, serial)
+	if c.fallback(err) {
+		return c.execStat(ctx, serial, remotePath)
+	}
+	if err != nil {
+		return FileInfo{}, err
+	}
+	defer s.Close()
+	e, err := s.stat(remotePath)
+	if err != nil {
+		return FileInfo{}, fmt.Errorf("adb stat %s: %w", remotePath, err)
+	}
+	return FileInfo{Path: remotePath, Size: e.size, MTime: e.mtime}, nil
 }
 
 // Pull copies a file from the device to the local filesystem.
 func (c *Client) Pull(serial, remotePath, localPath string) error {
-	out, err := newCmd(c.bin, "-s", serial, "pull", remotePath, localPath).CombinedOutput()
+	return c.PullCtx(context.Background(), serial, remotePath, localPath, nil)
+}
+
+// PullCtx is like Pull but accepts a context for cancellation and an
+// optional progress callback, which receives the number of bytes written so far.
+func (c *Client) PullCtx(ctx context.Context, serial, remotePath, localPath string, progress func(written int64)) error {
+	s, err := c.openSync(ctx, serial)
+	if c.fallback(err) {
+		return c.execPull(ctx, serial, remotePath, localPath, progress)
+	}
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	f, err := os.Create(localPath)
 	if err != nil {
-		return fmt.Errorf("adb pull %s: %w\n%s", remotePath, err, out)
+		return fmt.Errorf("adb pull %s: %w", remotePath, err)
+	}
+	bw := bufio.NewWriterSize(f, syncDataMax)
+	_, err = s.recv(remotePath, bw, progress)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return c.ctxErr(ctx, fmt.Errorf("adb pull %s: %w", remotePath, err))
 	}
 	return nil
 }
 
+// SHA256 hashes a file on the device with sha256sum and returns the hex digest.
+func (c *Client) SHA256(ctx context.Context, serial, remotePath string) (string, error) {
+	out, err := c.ShellCtx(ctx, serial, "sha256sum "+shellQuote(remotePath))
+	if err != nil {
+		return "", fmt.Errorf("adb sha256sum %s: %w", remotePath, err)
+	}
+	fields := strings.Fields(out)
+	if len(fields) == 0 || len(fields[0]) != 64 {
+		return "", fmt.Errorf("adb sha256sum %s: unexpected output %q", remotePath, strings.TrimSpace(out))
+	}
+	return strings.ToLower(fields[0]), nil
+}
+
 // Remove deletes a file on the device.
 func (c *Client) Remove(serial, remotePath string) error {
-	out, err := newCmd(c.bin, "-s", serial, "shell", "rm", remotePath).CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("adb rm %s: %w\n%s", remotePath, err, out)
+	if _, err := c.Shell(serial, "rm "+shellQuote(remotePath)); err != nil {
+		return fmt.Errorf("adb rm %s: %w", remotePath, err)
 	}
 	return nil
 }
@@ -153,8 +308,9 @@ func parseDeviceList(output string) []Device {
 			Serial: fields[0],
 			State:  fields[1],
 		}
-		// Determine connection type
-		if strings.Contains(d.Serial, ":") {
+		// Determine connection type. Devices the server found via mDNS
+		// are named after their service instance rather than ip:port.
+		if strings.Contains(d.Serial, ":") || strings.Contains(d.Serial, "._adb") {
 			d.ConnType = WiFi
 		} else {
 			d.ConnType = USB
@@ -0,0 +1,473 @@
+package adb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeFile is a file on a fake device.
+type fakeFile struct {
+	data  string
+	mtime time.Time
+}
+
+// fakeDevice is a headset attached to a fakeServer.
+type fakeDevice struct {
+	state    string // as listed by host:devices-l
+	features string // comma-separated, as answered to host-serial:<serial>:features
+	shell    map[string]fakeShell
+	files    map[string]fakeFile // absolute path -> file
+}
+
+// fakeShell is the outcome of a shell command.
+type fakeShell struct {
+	stdout, stderr string
+	exit           byte
+}
+
+// fakeServer speaks enough of the ADB server's host, sync and shell
+// protocols to exercise the client.
+type fakeServer struct {
+	t       *testing.T
+	ln      net.Listener
+	devices map[string]*fakeDevice // transport serial -> device
+}
+
+func newFakeServer(t *testing.T, devices map[string]*fakeDevice) *fakeServer {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeServer{t: t, ln: ln, devices: devices}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			nc, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(nc)
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) addr() string { return s.ln.Addr().String() }
+
+func (s *fakeServer) serve(nc net.Conn) {
+	defer nc.Close()
+	r := bufio.NewReader(nc)
+	var dev *fakeDevice // set once the connection is switched to a device
+	for {
+		req, err := readRequest(r)
+		if err != nil {
+			return
+		}
+		switch {
+		case req == "host:devices-l":
+			okay(nc, s.deviceList())
+		case strings.HasPrefix(req, "host-serial:") && strings.HasSuffix(req, ":features"):
+			serial := strings.TrimSuffix(strings.TrimPrefix(req, "host-serial:"), ":features")
+			d, ok := s.devices[serial]
+			if !ok {
+				fail(nc, fmt.Sprintf("device '%s' not found", serial))
+				return
+			}
+			okay(nc, d.features)
+		case strings.HasPrefix(req, "host:transport:"):
+			serial := strings.TrimPrefix(req, "host:transport:")
+			d, ok := s.devices[serial]
+			switch {
+			case !ok:
+				fail(nc, fmt.Sprintf("device '%s' not found", serial))
+				return
+			case d.state == "unauthorized":
+				fail(nc, "device unauthorized.\nThis adb server's $ADB_VENDOR_KEYS is not set")
+				return
+			}
+			dev = d
+			io.WriteString(nc, "OKAY")
+		case dev != nil && req == "sync:":
+			io.WriteString(nc, "OKAY")
+			s.serveSync(nc, r, dev)
+			return
+		case dev != nil && strings.HasPrefix(req, "shell,v2,raw:"):
+			io.WriteString(nc, "OKAY")
+			sh := dev.shell[strings.TrimPrefix(req, "shell,v2,raw:")]
+			writeShellPacket(nc, shellStdout, sh.stdout)
+			writeShellPacket(nc, shellStderr, sh.stderr)
+			writeShellPacket(nc, shellExit, string([]byte{sh.exit}))
+			return
+		case dev != nil && strings.HasPrefix(req, "shell:"):
+			io.WriteString(nc, "OKAY")
+			sh := dev.shell[strings.TrimPrefix(req, "shell:")]
+			io.WriteString(nc, sh.stdout+sh.stderr)
+			return
+		default:
+			fail(nc, "unknown host service")
+			return
+		}
+	}
+}
+
+func (s *fakeServer) deviceList() string {
+	var serials []string
+	for serial := range s.devices {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+	var b strings.Builder
+	for _, serial := range serials {
+		fmt.Fprintf(&b, "%s\t%s product:hollywood model:Quest_2 device:hollywood transport_id:1\n",
+			serial, s.devices[serial].state)
+	}
+	return b.String()
+}
+
+// serveSync answers v1 sync requests until QUIT.
+func (s *fakeServer) serveSync(nc net.Conn, r *bufio.Reader, dev *fakeDevice) {
+	le := binary.LittleEndian
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return
+		}
+		p := make([]byte, le.Uint32(hdr[4:]))
+		if _, err := io.ReadFull(r, p); err != nil {
+			return
+		}
+		switch id := string(hdr[:4]); id {
+		case "STAT":
+			mode, size, mtime := dev.stat(string(p))
+			var b [16]byte
+			copy(b[:], "STAT")
+			le.PutUint32(b[4:], mode)
+			le.PutUint32(b[8:], uint32(size))
+			le.PutUint32(b[12:], uint32(mtime.Unix()))
+			nc.Write(b[:])
+		case "LIST":
+			dir := strings.TrimRight(string(p), "/")
+			for _, name := range append([]string{".", ".."}, dev.children(dir)...) {
+				mode, size, mtime := dev.stat(path.Join(dir, name))
+				var b [20]byte
+				copy(b[:], "DENT")
+				le.PutUint32(b[4:], mode)
+				le.PutUint32(b[8:], uint32(size))
+				le.PutUint32(b[12:], uint32(mtime.Unix()))
+				le.PutUint32(b[16:], uint32(len(name)))
+				nc.Write(append(b[:], name...))
+			}
+			nc.Write([]byte("DONE\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00"))
+		case "RECV":
+			f, ok := dev.files[string(p)]
+			if !ok {
+				writeSyncPacket(nc, "FAIL", "No such file or directory")
+				continue
+			}
+			for data := f.data; len(data) > 0; {
+				n := min(len(data), 3) // several chunks, even for tiny files
+				writeSyncPacket(nc, "DATA", data[:n])
+				data = data[n:]
+			}
+			nc.Write([]byte("DONE\x00\x00\x00\x00"))
+		case "QUIT":
+			return
+		default:
+			s.t.Errorf("fake adb: unexpected sync request %q", id)
+			return
+		}
+	}
+}
+
+// stat returns the v1 mode, size and mtime of p; mode is 0 if p doesn't exist.
+func (d *fakeDevice) stat(p string) (uint32, int64, time.Time) {
+	if f, ok := d.files[p]; ok {
+		return modeRegular | 0o644, int64(len(f.data)), f.mtime
+	}
+	for name := range d.files {
+		if strings.HasPrefix(name, p+"/") {
+			return modeDir | 0o755, 4096, time.Unix(0, 0)
+		}
+	}
+	return 0, 0, time.Time{}
+}
+
+// children returns the names directly under dir.
+func (d *fakeDevice) children(dir string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for name := range d.files {
+		rest, ok := strings.CutPrefix(name, dir+"/")
+		if !ok {
+			continue
+		}
+		child, _, _ := strings.Cut(rest, "/")
+		if !seen[child] {
+			seen[child] = true
+			names = append(names, child)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readRequest(r *bufio.Reader) (string, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return "", err
+	}
+	n, err := strconv.ParseUint(string(hdr[:]), 16, 32)
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+func okay(w io.Writer, s string)   { fmt.Fprintf(w, "OKAY%04x%s", len(s), s) }
+func fail(w io.Writer, msg string) { fmt.Fprintf(w, "FAIL%04x%s", len(msg), msg) }
+
+func writeSyncPacket(w io.Writer, id, data string) {
+	b := make([]byte, 8, 8+len(data))
+	copy(b, id)
+	binary.LittleEndian.PutUint32(b[4:], uint32(len(data)))
+	w.Write(append(b, data...))
+}
+
+func writeShellPacket(w io.Writer, id byte, data string) {
+	b := make([]byte, 5, 5+len(data))
+	b[0] = id
+	binary.LittleEndian.PutUint32(b[1:], uint32(len(data)))
+	w.Write(append(b, data...))
+}
+
+var questMTime = time.Date(2025, 1, 31, 14, 25, 30, 0, time.UTC)
+
+func newQuest() *fakeDevice {
+	return &fakeDevice{
+		state: "device",
+		shell: map[string]fakeShell{
+			"getprop ro.serialno": {stdout: "1WMHH000000001\n"},
+			"echo hello":          {stdout: "hello\n"},
+			"false":               {stderr: "oops\n", exit: 1},
+		},
+		files: map[string]fakeFile{
+			"/sdcard/Oculus/VideoShots/com.beatgames.beatsaber-20250131-142530.mp4": {"0123456789", questMTime},
+			"/sdcard/Oculus/Screenshots/a.jpg":                                      {"jpeg", questMTime},
+			"/sdcard/Oculus/Screenshots/2025/b.jpg":                                 {"jpeg!", questMTime},
+		},
+	}
+}
+
+func TestDevices(t *testing.T) {
+	srv := newFakeServer(t, map[string]*fakeDevice{
+		"1WMHH000000001":    newQuest(),
+		"192.168.1.20:5555": newQuest(),
+		"1WMHH000000002":    {state: "unauthorized"},
+	})
+	c := NewServerClient(srv.addr())
+
+	devs, err := c.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devs) != 3 {
+		t.Fatalf("got %d devices, want 3: %+v", len(devs), devs)
+	}
+	byID := make(map[string]Device)
+	for _, d := range devs {
+		byID[d.Serial] = d
+	}
+	wifi := byID["192.168.1.20:5555"]
+	if wifi.ID != "1WMHH000000001" || wifi.ConnType != WiFi || wifi.Model != "Quest_2" {
+		t.Errorf("wifi device = %+v", wifi)
+	}
+	if d := byID["1WMHH000000002"]; d.IsOnline() || d.ID != d.Serial {
+		t.Errorf("unauthorized device = %+v", d)
+	}
+	if u := UniqueDevices(devs); len(u) != 2 {
+		t.Errorf("UniqueDevices gave %d headsets, want 2", len(u))
+	}
+
+	d, err := c.FindDevice("1WMHH000000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ConnType != USB {
+		t.Errorf("FindDevice picked %+v, want the USB transport", d)
+	}
+}
+
+func TestSync(t *testing.T) {
+	srv := newFakeServer(t, map[string]*fakeDevice{"1WMHH000000001": newQuest()})
+	c := NewServerClient(srv.addr())
+	const serial = "1WMHH000000001"
+
+	files, err := c.ListFilesRecursive(serial, "/sdcard/Oculus")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, f := range files {
+		paths = append(paths, f.Path)
+	}
+	sort.Strings(paths)
+	want := []string{
+		"/sdcard/Oculus/Screenshots/2025/b.jpg",
+		"/sdcard/Oculus/Screenshots/a.jpg",
+		"/sdcard/Oculus/VideoShots/com.beatgames.beatsaber-20250131-142530.mp4",
+	}
+	if strings.Join(paths, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ListFilesRecursive = %q, want %q", paths, want)
+	}
+
+	flat, err := c.ListFiles(serial, "/sdcard/Oculus/Screenshots")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(flat) != 1 || flat[0].Path != "/sdcard/Oculus/Screenshots/a.jpg" || flat[0].Size != 4 {
+		t.Errorf("ListFiles = %+v", flat)
+	}
+
+	video := want[2]
+	fi, err := c.Stat(serial, video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size != 10 || !fi.MTime.Equal(questMTime) {
+		t.Errorf("Stat = %+v", fi)
+	}
+	if _, err := c.Stat(serial, "/sdcard/missing.mp4"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Stat of a missing file: %v, want fs.ErrNotExist", err)
+	}
+
+	local := filepath.Join(t.TempDir(), "video.mp4")
+	var progress []int64
+	if err := c.PullCtx(t.Context(), serial, video, local, func(n int64) { progress = append(progress, n) }); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(local); string(data) != "0123456789" {
+		t.Errorf("pulled %q", data)
+	}
+	if len(progress) < 2 || progress[len(progress)-1] != 10 {
+		t.Errorf("progress = %v", progress)
+	}
+
+	err = c.Pull(serial, "/sdcard/missing.mp4", filepath.Join(t.TempDir(), "missing.mp4"))
+	var se *ServerError
+	if !errors.As(err, &se) || !strings.Contains(se.Message, "No such file") {
+		t.Errorf("Pull of a missing file: %v, want a *ServerError", err)
+	}
+}
+
+func TestShell(t *testing.T) {
+	v1 := newQuest()
+	v2 := newQuest()
+	v2.features = "shell_v2,cmd"
+	srv := newFakeServer(t, map[string]*fakeDevice{"old": v1, "new": v2})
+	c := NewServerClient(srv.addr())
+
+	for _, serial := range []string{"old", "new"} {
+		out, err := c.Shell(serial, "echo hello")
+		if err != nil || out != "hello\n" {
+			t.Errorf("%s: Shell = %q, %v", serial, out, err)
+		}
+	}
+
+	_, err := c.Shell("new", "false")
+	var se *ShellError
+	if !errors.As(err, &se) || se.ExitCode != 1 || se.Output != "oops\n" {
+		t.Errorf("Shell with shell_v2: %v, want a *ShellError with exit status 1", err)
+	}
+}
+
+func TestServerFailures(t *testing.T) {
+	srv := newFakeServer(t, map[string]*fakeDevice{"locked": {state: "unauthorized"}})
+	c := NewServerClient(srv.addr())
+
+	tests := []struct {
+		serial string
+		want   error
+	}{
+		{"gone", ErrDeviceNotFound},
+		{"locked", ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		_, err := c.Shell(tt.serial, "echo hello")
+		var se *ServerError
+		if !errors.As(err, &se) {
+			t.Errorf("%s: %v, want a *ServerError", tt.serial, err)
+		}
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: %v, want errors.Is %v", tt.serial, err, tt.want)
+		}
+	}
+}
+
+// closedAddr returns an address nothing is listening on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestServerUnavailable(t *testing.T) {
+	c := NewServerClient(closedAddr(t))
+	if _, err := c.Devices(); !errors.Is(err, ErrServerUnavailable) {
+		t.Errorf("Devices without a server: %v, want ErrServerUnavailable", err)
+	}
+}
+
+func TestExecFallback(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake adb binary is a shell script")
+	}
+	// A stand-in for the adb binary, used once the server can't be reached.
+	bin := filepath.Join(t.TempDir(), "adb")
+	script := `#!/bin/sh
+case "$*" in
+start-server) exit 0 ;;
+"devices -l") printf 'List of devices attached\n1WMHH000000001\tdevice product:hollywood model:Quest_2 transport_id:3\n' ;;
+"-s 1WMHH000000001 shell getprop ro.serialno") echo 1WMHH000000001 ;;
+"-s 1WMHH000000001 shell echo hello") echo hello ;;
+*) echo "unexpected: $*" >&2; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := NewClient(bin)
+	c.addr = closedAddr(t)
+
+	devs, err := c.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devs) != 1 || devs[0].ID != "1WMHH000000001" || devs[0].Model != "Quest_2" {
+		t.Errorf("Devices = %+v", devs)
+	}
+	if out, err := c.Shell("1WMHH000000001", "echo hello"); err != nil || out != "hello\n" {
+		t.Errorf("Shell = %q, %v", out, err)
+	}
+}
@@ -1,5 +1,7 @@
 package adb
 
+import "fmt"
+
 // ConnectionType indicates how a device is connected.
 type ConnectionType string
 
@@ -11,11 +13,12 @@ const (
 
 // Device represents a connected ADB device.
 type Device struct {
-	Serial     string
-	State      string // "device", "offline", "unauthorized", etc.
-	ConnType   ConnectionType
-	Model      string
-	Product    string
+	Serial      string // transport serial: USB serial, or ip:port over WiFi
+	ID          string // hardware serial (ro.serialno), the same over every transport
+	State       string // "device", "offline", "unauthorized", etc.
+	ConnType    ConnectionType
+	Model       string
+	Product     string
 	TransportID string
 }
 
@@ -23,3 +26,52 @@ type Device struct {
 func (d Device) IsOnline() bool {
 	return d.State == "device"
 }
+
+// UniqueDevices collapses devices attached over several transports into
+// one entry per headset. An online transport wins over an offline one,
+// and USB wins over WiFi.
+func UniqueDevices(devs []Device) []Device {
+	var out []Device
+	index := make(map[string]int)
+	for _, d := range devs {
+		i, seen := index[d.ID]
+		if !seen {
+			index[d.ID] = len(out)
+			out = append(out, d)
+			continue
+		}
+		if better(d, out[i]) {
+			out[i] = d
+		}
+	}
+	return out
+}
+
+func better(a, b Device) bool {
+	if a.IsOnline() != b.IsOnline() {
+		return a.IsOnline()
+	}
+	return a.ConnType == USB && b.ConnType != USB
+}
+
+// FindDevice returns the online device whose transport serial or hardware
+// serial matches serial, preferring USB if the headset is attached twice.
+func (c *Client) FindDevice(serial string) (Device, error) {
+	devs, err := c.Devices()
+	if err != nil {
+		return Device{}, err
+	}
+	id := ""
+	for _, d := range devs {
+		if d.IsOnline() && (d.ID == serial || d.Serial == serial) {
+			id = d.ID
+			break
+		}
+	}
+	for _, d := range UniqueDevices(devs) {
+		if id != "" && d.ID == id {
+			return d, nil
+		}
+	}
+	return Device{}, fmt.Errorf("%w: %s", ErrDeviceNotFound, serial)
+}
@@ -0,0 +1,58 @@
+package adb
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrServerUnavailable means no ADB server could be reached or started.
+	ErrServerUnavailable = errors.New("adb server unavailable")
+	// ErrDeviceNotFound means the requested serial is not attached.
+	ErrDeviceNotFound = errors.New("device not found")
+	// ErrDeviceOffline means the device is attached but not responding.
+	ErrDeviceOffline = errors.New("device offline")
+	// ErrUnauthorized means USB debugging has not been allowed on the headset.
+	ErrUnauthorized = errors.New("device unauthorized")
+)
+
+// ServerError is a FAIL response from the ADB server or the device.
+type ServerError struct {
+	Request string
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("adb %s: %s", e.Request, e.Message)
+}
+
+// Unwrap maps well-known server messages to the package's sentinel errors,
+// so callers can use errors.Is(err, adb.ErrUnauthorized) and friends.
+func (e *ServerError) Unwrap() error {
+	msg := strings.ToLower(e.Message)
+	switch {
+	case strings.Contains(msg, "unauthorized"):
+		return ErrUnauthorized
+	case strings.Contains(msg, "offline"):
+		return ErrDeviceOffline
+	case strings.Contains(msg, "not found"), strings.Contains(msg, "no devices"):
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// ShellError reports a shell command that exited with a non-zero status.
+type ShellError struct {
+	Command  string
+	ExitCode int
+	Output   string
+}
+
+func (e *ShellError) Error() string {
+	out := strings.TrimSpace(e.Output)
+	if out == "" {
+		return fmt.Sprintf("adb shell %s: exit status %d", e.Command, e.ExitCode)
+	}
+	return fmt.Sprintf("adb shell %s: exit status %d\n%s", e.Command, e.ExitCode, out)
+}
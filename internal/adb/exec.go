@@ -0,0 +1,114 @@
+package adb
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"time"
+)
+
+// The functions in this file drive the adb binary directly. They are the
+// fallback used when the ADB server can't be reached over its socket.
+
+func (c *Client) execDevices() ([]Device, error) {
+	out, err := newCmd(c.bin, "devices", "-l").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("adb devices: %w\n%s", err, out)
+	}
+	return parseDeviceList(string(out)), nil
+}
+
+func (c *Client) execConnect(addr string) (string, error) {
+	out, err := newCmd(c.bin, "connect", addr).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("adb connect %s: %w\n%s", addr, err, out)
+	}
+	return string(out), nil
+}
+
+func (c *Client) execListFiles(ctx context.Context, serial, remotePath string, recursive bool) ([]FileInfo, error) {
+	depth := ""
+	if !recursive {
+		depth = "-maxdepth 1 "
+	}
+	out, err := newCmdContext(ctx, c.bin, "-s", serial, "shell",
+		fmt.Sprintf("find %s %s-type f -exec stat -c '%%s %%Y %%n' {} +", shellQuote(remotePath), depth),
+	).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Directory might not exist
+		if strings.Contains(string(out), "No such file") {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("adb shell find %s: %w\n%s", remotePath, err, out)
+	}
+	return parseStatOutput(string(out)), nil
+}
+
+func (c *Client) execStat(ctx context.Context, serial, remotePath string) (FileInfo, error) {
+	out, err := c.execShell(ctx, serial, "stat -c '%s %Y %n' "+shellQuote(remotePath))
+	if err != nil {
+		if strings.Contains(err.Error(), "No such file") {
+			return FileInfo{}, &fs.PathError{Op: "stat", Path: remotePath, Err: fs.ErrNotExist}
+		}
+		return FileInfo{}, err
+	}
+	files := parseStatOutput(out)
+	if len(files) != 1 {
+		return FileInfo{}, fmt.Errorf("adb stat %s: unexpected output %q", remotePath, out)
+	}
+	return files[0], nil
+}
+
+// execPull runs `adb pull`. Without a byte stream to observe, progress is
+// estimated by polling the size of the local file while adb writes it.
+func (c *Client) execPull(ctx context.Context, serial, remotePath, localPath string, progress func(int64)) error {
+	cmd := newCmdContext(ctx, c.bin, "-s", serial, "pull", remotePath, localPath)
+	var out strings.Builder
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("adb pull %s: %w", remotePath, err)
+	}
+	done := make(chan struct{})
+	if progress != nil {
+		go func() {
+			ticker := time.NewTicker(300 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-done:
+					return
+				case <-ticker.C:
+					if info, err := os.Stat(localPath); err == nil {
+						progress(info.Size())
+					}
+				}
+			}
+		}()
+	}
+	err := cmd.Wait()
+	close(done)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("adb pull %s: %w\n%s", remotePath, err, out.String())
+	}
+	return nil
+}
+
+func (c *Client) execShell(ctx context.Context, serial, command string) (string, error) {
+	out, err := newCmdContext(ctx, c.bin, "-s", serial, "shell", command).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return "", ctx.Err()
+		}
+		return "", fmt.Errorf("adb shell %s: %w\n%s", command, err, out)
+	}
+	return string(out), nil
+}
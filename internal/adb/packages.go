@@ -0,0 +1,65 @@
+package adb
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+)
+
+// Packages lists the packages installed on the device, from
+// `pm list packages`.
+func (c *Client) Packages(serial string) ([]string, error) {
+	out, err := c.Shell(serial, "pm list packages")
+	if err != nil {
+		return nil, fmt.Errorf("pm list packages: %w", err)
+	}
+	return parsePackages(out), nil
+}
+
+// parsePackages parses `pm list packages` output:
+//
+//	package:com.beatgames.beatsaber
+//	package:com.oculus.vrshell
+func parsePackages(output string) []string {
+	var pkgs []string
+	sc := bufio.NewScanner(strings.NewReader(output))
+	for sc.Scan() {
+		if pkg, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "package:"); ok && pkg != "" {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs
+}
+
+// AppLabel returns the name a package shows in the app library, from
+// `dumpsys package`. Not every Android build prints it; then it returns "".
+func (c *Client) AppLabel(serial, pkg string) (string, error) {
+	out, err := c.Shell(serial, "dumpsys package "+shellQuote(pkg))
+	if err != nil {
+		return "", fmt.Errorf("dumpsys package %s: %w", pkg, err)
+	}
+	return parseAppLabel(out), nil
+}
+
+// parseAppLabel finds the application label in `dumpsys package` output,
+// which depending on the Android version appears as one of:
+//
+//	applicationLabel=Beat Saber
+//	label=Beat Saber
+//	nonLocalizedLabel=Beat Saber
+func parseAppLabel(output string) string {
+	sc := bufio.NewScanner(strings.NewReader(output))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		for _, key := range []string{"applicationLabel=", "nonLocalizedLabel=", "label="} {
+			v, ok := strings.CutPrefix(line, key)
+			if !ok {
+				continue
+			}
+			if v = strings.TrimSpace(v); v != "" && v != "null" && !strings.HasPrefix(v, "0x") {
+				return v
+			}
+		}
+	}
+	return ""
+}
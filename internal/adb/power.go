@@ -0,0 +1,86 @@
+package adb
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Battery is the headset's battery state as reported by `dumpsys battery`.
+type Battery struct {
+	Level    int  // percent
+	Charging bool // plugged into a power source
+}
+
+// Battery reads the device's battery level and whether it is plugged in.
+func (c *Client) Battery(serial string) (Battery, error) {
+	out, err := c.Shell(serial, "dumpsys battery")
+	if err != nil {
+		return Battery{}, fmt.Errorf("dumpsys battery: %w", err)
+	}
+	return parseBattery(out)
+}
+
+// parseBattery parses `dumpsys battery` output:
+//
+//	Current Battery Service state:
+//	  AC powered: false
+//	  USB powered: true
+//	  Wireless powered: false
+//	  status: 2
+//	  level: 85
+//	  scale: 100
+func parseBattery(output string) (Battery, error) {
+	var b Battery
+	level, scale := -1, 100
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, val, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok {
+			continue
+		}
+		val = strings.TrimSpace(val)
+		switch key {
+		case "AC powered", "USB powered", "Wireless powered", "Dock powered":
+			if val == "true" {
+				b.Charging = true
+			}
+		case "level":
+			level, _ = strconv.Atoi(val)
+		case "scale":
+			if n, err := strconv.Atoi(val); err == nil && n > 0 {
+				scale = n
+			}
+		}
+	}
+	if level < 0 {
+		return b, fmt.Errorf("dumpsys battery: no level in output")
+	}
+	b.Level = level * 100 / scale
+	return b, nil
+}
+
+// KeepAwake stops the device from sleeping while it is on power, for the
+// duration of a transfer. The returned function puts the previous setting
+// back.
+func (c *Client) KeepAwake(serial string) (restore func() error, err error) {
+	out, err := c.Shell(serial, "settings get global stay_on_while_plugged_in")
+	if err != nil {
+		return nil, fmt.Errorf("read stay-awake setting: %w", err)
+	}
+	prev, err := strconv.Atoi(strings.TrimSpace(out))
+	if err != nil {
+		prev = 0 // "null": never set, which means off
+	}
+	if _, err := c.Shell(serial, "svc power stayon true"); err != nil {
+		return nil, fmt.Errorf("svc power stayon: %w", err)
+	}
+	return func() error {
+		_, err := c.Shell(serial, fmt.Sprintf("settings put global stay_on_while_plugged_in %d", prev))
+		if err != nil {
+			return fmt.Errorf("restore stay-awake setting: %w", err)
+		}
+		return nil
+	}, nil
+}
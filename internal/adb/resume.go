@@ -0,0 +1,84 @@
+package adb
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// PullFrom resumes an interrupted pull: it keeps the first offset bytes of
+// localPath and appends the rest of remotePath after them. progress, if
+// non-nil, receives the total size of localPath so far.
+//
+// The sync protocol can't start a transfer part-way through, so the tail
+// of the file is streamed with `tail -c` over the raw exec: service.
+func (c *Client) PullFrom(ctx context.Context, serial, remotePath, localPath string, offset int64, progress func(written int64)) error {
+	f, err := os.OpenFile(localPath, os.O_WRONLY, 0)
+	if err != nil {
+		return fmt.Errorf("adb pull %s: %w", remotePath, err)
+	}
+	if err := f.Truncate(offset); err != nil {
+		f.Close()
+		return fmt.Errorf("adb pull %s: %w", remotePath, err)
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		f.Close()
+		return fmt.Errorf("adb pull %s: %w", remotePath, err)
+	}
+
+	bw := bufio.NewWriterSize(f, syncDataMax)
+	w := &countingWriter{w: bw, n: offset, progress: progress}
+	command := fmt.Sprintf("tail -c +%d %s", offset+1, shellQuote(remotePath))
+	err = c.execOut(ctx, serial, command, w)
+	if err == nil {
+		err = bw.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return c.ctxErr(ctx, fmt.Errorf("adb pull %s from byte %d: %w", remotePath, offset, err))
+	}
+	return nil
+}
+
+// execOut runs command on the device and copies its raw stdout to w.
+// Unlike shell:, exec: never allocates a pty, so binary data is untouched.
+func (c *Client) execOut(ctx context.Context, serial, command string, w io.Writer) error {
+	cn, err := c.service(ctx, serial, "exec:"+command)
+	if c.fallback(err) {
+		cmd := newCmdContext(ctx, c.bin, "-s", serial, "exec-out", command)
+		var stderr strings.Builder
+		cmd.Stdout = w
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%w\n%s", err, stderr.String())
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+	_, err = io.Copy(w, cn.r)
+	return err
+}
+
+// countingWriter reports the running byte count after each write.
+type countingWriter struct {
+	w        io.Writer
+	n        int64
+	progress func(int64)
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	if cw.progress != nil {
+		cw.progress(cw.n)
+	}
+	return n, err
+}
@@ -0,0 +1,88 @@
+package adb
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Shell protocol v2 packet ids.
+const (
+	shellStdout = 1
+	shellStderr = 2
+	shellExit   = 3
+)
+
+// Shell runs a command on the device and returns its stdout.
+func (c *Client) Shell(serial, command string) (string, error) {
+	return c.ShellCtx(context.Background(), serial, command)
+}
+
+// ShellCtx is like Shell but accepts a context for cancellation.
+// A non-zero exit status is reported as a *ShellError.
+func (c *Client) ShellCtx(ctx context.Context, serial, command string) (string, error) {
+	var stdout bytes.Buffer
+	err := c.shellStream(ctx, serial, command, &stdout)
+	if c.fallback(err) {
+		return c.execShell(ctx, serial, command)
+	}
+	return stdout.String(), err
+}
+
+// shellStream runs command and copies its stdout to w. Devices that
+// support shell v2 report stderr and the exit status separately; on older
+// devices stdout and stderr are merged and the exit status is unknown.
+func (c *Client) shellStream(ctx context.Context, serial, command string, w io.Writer) error {
+	v2 := c.features(ctx, serial)["shell_v2"]
+	svc := "shell:" + command
+	if v2 {
+		svc = "shell,v2,raw:" + command
+	}
+	cn, err := c.service(ctx, serial, svc)
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+
+	if !v2 {
+		_, err := io.Copy(w, cn.r)
+		return c.ctxErr(ctx, err)
+	}
+
+	var stderr bytes.Buffer
+	var hdr [5]byte
+	for {
+		if _, err := io.ReadFull(cn.r, hdr[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return c.ctxErr(ctx, fmt.Errorf("adb shell %s: %w", command, err))
+		}
+		n := int64(binary.LittleEndian.Uint32(hdr[1:]))
+		switch hdr[0] {
+		case shellStdout:
+			if _, err := io.CopyN(w, cn.r, n); err != nil {
+				return c.ctxErr(ctx, fmt.Errorf("adb shell %s: %w", command, err))
+			}
+		case shellExit:
+			var code [1]byte
+			if _, err := io.ReadFull(cn.r, code[:]); err != nil {
+				return c.ctxErr(ctx, err)
+			}
+			if code[0] != 0 {
+				return &ShellError{Command: command, ExitCode: int(code[0]), Output: stderr.String()}
+			}
+			return nil
+		default: // stderr and anything we don't use
+			dst := io.Discard
+			if hdr[0] == shellStderr {
+				dst = &stderr
+			}
+			if _, err := io.CopyN(dst, cn.r, n); err != nil {
+				return c.ctxErr(ctx, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package adb
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DiskUsage describes the filesystem holding a path on the device.
+type DiskUsage struct {
+	Total int64 // bytes
+	Used  int64
+	Free  int64 // available to apps
+}
+
+// DiskUsage reports total, used and free space of the filesystem that
+// holds path (usually /sdcard), using `df` on the device.
+func (c *Client) DiskUsage(serial, path string) (DiskUsage, error) {
+	out, err := c.Shell(serial, "df -k "+shellQuote(path))
+	if err != nil {
+		return DiskUsage{}, fmt.Errorf("df %s: %w", path, err)
+	}
+	u, err := parseDF(out)
+	if err != nil {
+		return DiskUsage{}, fmt.Errorf("df %s: %w", path, err)
+	}
+	return u, nil
+}
+
+// parseDF parses `df -k` output for a single path:
+//
+//	Filesystem     1K-blocks     Used Available Use% Mounted on
+//	/dev/fuse      111564240 40125880  71307288  37% /storage/emulated
+func parseDF(output string) (DiskUsage, error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	fields := strings.Fields(lines[len(lines)-1])
+	if len(lines) < 2 || len(fields) < 4 {
+		return DiskUsage{}, fmt.Errorf("unexpected output %q", output)
+	}
+	var kb [3]int64
+	for i := range kb {
+		n, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil {
+			return DiskUsage{}, fmt.Errorf("unexpected output %q", output)
+		}
+		kb[i] = n * 1024
+	}
+	return DiskUsage{Total: kb[0], Used: kb[1], Free: kb[2]}, nil
+}
@@ -0,0 +1,240 @@
+package adb
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"time"
+)
+
+// File mode bits reported by the sync service (from <sys/stat.h>).
+const (
+	modeTypeMask = 0o170000
+	modeDir      = 0o040000
+	modeRegular  = 0o100000
+)
+
+// syncDataMax is the largest DATA chunk the sync protocol allows.
+const syncDataMax = 64 * 1024
+
+// syncConn is an open sync: service on one device. v2 selects the
+// STA2/LIS2 requests, which report 64-bit sizes; v1 sizes wrap at 4 GiB.
+type syncConn struct {
+	cn *conn
+	v2 bool
+}
+
+// syncEntry is a file as reported by STAT or LIST.
+type syncEntry struct {
+	name  string
+	mode  uint32
+	size  int64
+	mtime time.Time
+}
+
+func (e syncEntry) isDir() bool     { return e.mode&modeTypeMask == modeDir }
+func (e syncEntry) isRegular() bool { return e.mode&modeTypeMask == modeRegular }
+
+func (c *Client) openSync(ctx context.Context, serial string) (*syncConn, error) {
+	feats := c.features(ctx, serial)
+	cn, err := c.service(ctx, serial, "sync:")
+	if err != nil {
+		return nil, err
+	}
+	return &syncConn{cn: cn, v2: feats["stat_v2"] && feats["ls_v2"]}, nil
+}
+
+func (s *syncConn) Close() error {
+	_ = s.send("QUIT", "")
+	return s.cn.Close()
+}
+
+// send writes a sync request: 4-byte id, little-endian length, payload.
+func (s *syncConn) send(id, payload string) error {
+	buf := make([]byte, 8+len(payload))
+	copy(buf, id)
+	binary.LittleEndian.PutUint32(buf[4:], uint32(len(payload)))
+	copy(buf[8:], payload)
+	_, err := s.cn.nc.Write(buf)
+	return err
+}
+
+func (s *syncConn) readID() (string, error) {
+	var id [4]byte
+	if _, err := io.ReadFull(s.cn.r, id[:]); err != nil {
+		return "", err
+	}
+	return string(id[:]), nil
+}
+
+// readFail reads the message that follows a FAIL id.
+func (s *syncConn) readFail(op, p string) error {
+	n, err := s.cn.readUint32()
+	if err != nil {
+		return err
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(s.cn.r, msg); err != nil {
+		return err
+	}
+	return &ServerError{Request: op + " " + p, Message: string(msg)}
+}
+
+// readStat2 reads the body shared by STA2 responses and DNT2 entries.
+func (s *syncConn) readStat2() (syncEntry, uint32, error) {
+	var b [68]byte
+	if _, err := io.ReadFull(s.cn.r, b[:]); err != nil {
+		return syncEntry{}, 0, err
+	}
+	le := binary.LittleEndian
+	// error(4) dev(8) ino(8) mode(4) nlink(4) uid(4) gid(4) size(8) atime(8) mtime(8) ctime(8)
+	e := syncEntry{
+		mode:  le.Uint32(b[20:]),
+		size:  int64(le.Uint64(b[36:])),
+		mtime: time.Unix(int64(le.Uint64(b[52:])), 0),
+	}
+	return e, le.Uint32(b[0:]), nil
+}
+
+// stat returns the entry for p. A missing file yields an fs.ErrNotExist.
+func (s *syncConn) stat(p string) (syncEntry, error) {
+	id := "STAT"
+	if s.v2 {
+		id = "STA2"
+	}
+	if err := s.send(id, p); err != nil {
+		return syncEntry{}, err
+	}
+	got, err := s.readID()
+	if err != nil {
+		return syncEntry{}, err
+	}
+	if got != id {
+		return syncEntry{}, fmt.Errorf("sync stat %s: unexpected response %q", p, got)
+	}
+	var e syncEntry
+	if s.v2 {
+		var errno uint32
+		if e, errno, err = s.readStat2(); err != nil {
+			return e, err
+		}
+		if errno != 0 {
+			return e, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+		}
+	} else {
+		var b [12]byte
+		if _, err := io.ReadFull(s.cn.r, b[:]); err != nil {
+			return e, err
+		}
+		le := binary.LittleEndian
+		e = syncEntry{
+			mode:  le.Uint32(b[0:]),
+			size:  int64(le.Uint32(b[4:])),
+			mtime: time.Unix(int64(le.Uint32(b[8:])), 0),
+		}
+		if e.mode == 0 {
+			return e, &fs.PathError{Op: "stat", Path: p, Err: fs.ErrNotExist}
+		}
+	}
+	e.name = path.Base(p)
+	return e, nil
+}
+
+// list calls fn for every entry in dir, excluding "." and "..".
+// A directory that does not exist lists as empty.
+func (s *syncConn) list(dir string, fn func(syncEntry)) error {
+	id, dent := "LIST", "DENT"
+	if s.v2 {
+		id, dent = "LIS2", "DNT2"
+	}
+	if err := s.send(id, dir); err != nil {
+		return err
+	}
+	le := binary.LittleEndian
+	for {
+		got, err := s.readID()
+		if err != nil {
+			return err
+		}
+		var e syncEntry
+		var namelen uint32
+		if s.v2 {
+			if e, _, err = s.readStat2(); err != nil {
+				return err
+			}
+			if namelen, err = s.cn.readUint32(); err != nil {
+				return err
+			}
+		} else {
+			var b [16]byte
+			if _, err := io.ReadFull(s.cn.r, b[:]); err != nil {
+				return err
+			}
+			e = syncEntry{
+				mode:  le.Uint32(b[0:]),
+				size:  int64(le.Uint32(b[4:])),
+				mtime: time.Unix(int64(le.Uint32(b[8:])), 0),
+			}
+			namelen = le.Uint32(b[12:])
+		}
+		switch got {
+		case "DONE":
+			return nil
+		case dent:
+		default:
+			return fmt.Errorf("sync list %s: unexpected response %q", dir, got)
+		}
+		name := make([]byte, namelen)
+		if _, err := io.ReadFull(s.cn.r, name); err != nil {
+			return err
+		}
+		e.name = string(name)
+		if e.name == "." || e.name == ".." {
+			continue
+		}
+		fn(e)
+	}
+}
+
+// recv streams the contents of p into w, calling progress (if non-nil)
+// with the running byte count after each chunk.
+func (s *syncConn) recv(p string, w io.Writer, progress func(int64)) (int64, error) {
+	if err := s.send("RECV", p); err != nil {
+		return 0, err
+	}
+	var written int64
+	for {
+		id, err := s.readID()
+		if err != nil {
+			return written, err
+		}
+		switch id {
+		case "DATA":
+			n, err := s.cn.readUint32()
+			if err != nil {
+				return written, err
+			}
+			if n > syncDataMax {
+				return written, fmt.Errorf("sync recv %s: oversized chunk (%d bytes)", p, n)
+			}
+			m, err := io.CopyN(w, s.cn.r, int64(n))
+			written += m
+			if err != nil {
+				return written, err
+			}
+			if progress != nil {
+				progress(written)
+			}
+		case "DONE":
+			_, err := s.cn.readUint32()
+			return written, err
+		case "FAIL":
+			return written, s.readFail("pull", p)
+		default:
+			return written, fmt.Errorf("sync recv %s: unexpected response %q", p, id)
+		}
+	}
+}
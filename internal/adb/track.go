@@ -0,0 +1,94 @@
+package adb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// pollInterval is how often TrackDevices polls when the ADB server can't
+// stream device changes.
+const pollInterval = 2 * time.Second
+
+// TrackDevices calls fn with the full device list once at the start and
+// again whenever a device is attached, detached or changes state. It keeps
+// going across ADB server restarts and returns only when ctx is done.
+//
+// It uses the server's host:track-devices-l stream, and falls back to
+// polling Devices when the server can't be reached over its socket.
+func (c *Client) TrackDevices(ctx context.Context, fn func([]Device)) error {
+	for {
+		err := c.track(ctx, fn)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var se *ServerError
+		if c.fallback(err) || errors.As(err, &se) {
+			// No socket, or a server too old to stream changes.
+			return c.poll(ctx, fn)
+		}
+		// The server went away (adb kill-server, upgrade, ...). Give it a
+		// moment and reconnect; dial restarts it if needed.
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (c *Client) track(ctx context.Context, fn func([]Device)) error {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+	if err := cn.request("host:track-devices-l"); err != nil {
+		return c.ctxErr(ctx, err)
+	}
+	for {
+		out, err := cn.readString()
+		if err != nil {
+			return c.ctxErr(ctx, fmt.Errorf("adb track-devices: %w", err))
+		}
+		devs := parseDeviceList(out)
+		c.identify(devs)
+		fn(devs)
+	}
+}
+
+func (c *Client) poll(ctx context.Context, fn func([]Device)) error {
+	var last []Device
+	first := true
+	for {
+		devs, err := c.Devices()
+		if err == nil && (first || !sameDevices(devs, last)) {
+			fn(devs)
+			last, first = devs, false
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// sameDevices reports whether two device lists have the same transports in
+// the same states.
+func sameDevices(a, b []Device) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	state := make(map[string]string, len(a))
+	for _, d := range a {
+		state[d.Serial] = d.State
+	}
+	for _, d := range b {
+		if s, ok := state[d.Serial]; !ok || s != d.State {
+			return false
+		}
+	}
+	return true
+}
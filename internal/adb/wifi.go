@@ -0,0 +1,166 @@
+package adb
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultWiFiPort is the port adbd listens on after `adb tcpip`.
+const DefaultWiFiPort = 5555
+
+// connectTimeout bounds each `adb connect`, so a headset that is switched
+// off doesn't hold up the scan for long.
+const connectTimeout = 3 * time.Second
+
+// ConnectAll runs `adb connect` for every address (ip:port) that isn't
+// already attached and online. Connections are attempted concurrently.
+// It returns the error for each address that could not be connected.
+func (c *Client) ConnectAll(addrs []string) map[string]error {
+	errs := make(map[string]error)
+	if len(addrs) == 0 {
+		return errs
+	}
+	attached := make(map[string]bool)
+	if devs, err := c.Devices(); err == nil {
+		for _, d := range devs {
+			if d.IsOnline() {
+				attached[d.Serial] = true
+			}
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		if attached[addr] {
+			continue
+		}
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+			defer cancel()
+			if err := c.connect(ctx, addr); err != nil {
+				mu.Lock()
+				errs[addr] = err
+				mu.Unlock()
+			}
+		}(addr)
+	}
+	wg.Wait()
+	return errs
+}
+
+var pairGUIDRe = regexp.MustCompile(`\[guid=([^\]]+)\]`)
+
+// Pair pairs with a headset in wireless debugging mode, using the six-digit
+// code and the ip:port shown in its "Pair device with pairing code" dialog.
+// It returns the device's adb GUID, which names its mDNS service instance
+// (and thereby its transport serial when the server auto-connects).
+func (c *Client) Pair(addr, code string) (string, error) {
+	output, err := c.hostQuery(context.Background(), "host:pair:"+code+":"+addr)
+	if c.fallback(err) {
+		var out []byte
+		out, err = newCmd(c.bin, "pair", addr, code).CombinedOutput()
+		output = string(out)
+		if err != nil {
+			return "", fmt.Errorf("adb pair %s: %w\n%s", addr, err, out)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+	if !strings.Contains(output, "Successfully paired") {
+		return "", fmt.Errorf("adb pair %s: %s", addr, strings.TrimSpace(output))
+	}
+	guid := ""
+	if m := pairGUIDRe.FindStringSubmatch(output); m != nil {
+		guid = m[1]
+	}
+	return guid, nil
+}
+
+// pairWait is how long PairDevice waits for a freshly paired headset to attach.
+const pairWait = 10 * time.Second
+
+// PairDevice pairs with the headset at pairAddr and waits for it to attach.
+// If connectAddr (the ip:port from the wireless debugging screen) is given it
+// is connected to explicitly; otherwise the ADB server is relied upon to
+// connect via mDNS, which it does for paired devices by default.
+func (c *Client) PairDevice(pairAddr, code, connectAddr string) (Device, error) {
+	guid, err := c.Pair(pairAddr, code)
+	if err != nil {
+		return Device{}, err
+	}
+	deadline := time.Now().Add(pairWait)
+	for {
+		if connectAddr != "" {
+			ctx, cancel := context.WithTimeout(context.Background(), connectTimeout)
+			err = c.connect(ctx, connectAddr)
+			cancel()
+		}
+		if devs, derr := c.Devices(); derr == nil {
+			for _, d := range devs {
+				if !d.IsOnline() {
+					continue
+				}
+				if (connectAddr != "" && d.Serial == connectAddr) ||
+					(guid != "" && strings.HasPrefix(d.Serial, guid)) {
+					return d, nil
+				}
+			}
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+	if err != nil {
+		return Device{}, fmt.Errorf("paired, but could not connect: %w", err)
+	}
+	return Device{}, fmt.Errorf("paired, but the headset did not connect — pass its wireless debugging address (ip:port) to connect explicitly")
+}
+
+// TCPIP restarts adbd on the device in TCP mode, listening on port.
+// The device drops its USB connection briefly while adbd restarts.
+func (c *Client) TCPIP(serial string, port int) error {
+	ctx := context.Background()
+	svc := fmt.Sprintf("tcpip:%d", port)
+	cn, err := c.service(ctx, serial, svc)
+	if c.fallback(err) {
+		out, err := newCmd(c.bin, "-s", serial, "tcpip", fmt.Sprint(port)).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("adb tcpip %d: %w\n%s", port, err, out)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer cn.Close()
+	out, _ := io.ReadAll(cn.r)
+	if !strings.Contains(string(out), "restarting") {
+		return fmt.Errorf("adb tcpip %d: %s", port, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+var inetAddrRe = regexp.MustCompile(`inet (\d+\.\d+\.\d+\.\d+)/`)
+
+// WiFiIP returns the device's IPv4 address on its wlan0 interface.
+func (c *Client) WiFiIP(serial string) (string, error) {
+	out, err := c.Shell(serial, "ip -f inet addr show wlan0")
+	if err != nil {
+		return "", fmt.Errorf("read wlan0 address: %w", err)
+	}
+	m := inetAddrRe.FindStringSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("no WiFi address found — is the headset connected to WiFi?")
+	}
+	return m[1], nil
+}
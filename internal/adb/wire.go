@@ -0,0 +1,211 @@
+package adb
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultServerPort is the port the ADB server listens on unless overridden
+// with ANDROID_ADB_SERVER_PORT (the same variable the adb binary honors).
+const defaultServerPort = 5037
+
+const dialTimeout = 2 * time.Second
+
+func defaultServerAddr() string {
+	port := defaultServerPort
+	if p, err := strconv.Atoi(os.Getenv("ANDROID_ADB_SERVER_PORT")); err == nil && p > 0 {
+		port = p
+	}
+	return net.JoinHostPort("127.0.0.1", strconv.Itoa(port))
+}
+
+// conn is a single connection to the ADB server. Each host request or
+// device service uses its own connection, as the protocol requires.
+type conn struct {
+	nc   net.Conn
+	r    *bufio.Reader
+	stop func() bool
+}
+
+// dial connects to the ADB server, starting it with the adb binary if it is
+// not running yet. It returns ErrServerUnavailable if no server can be reached.
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	if c.addr == "" {
+		return nil, ErrServerUnavailable
+	}
+	d := net.Dialer{Timeout: dialTimeout}
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil && ctx.Err() == nil && c.bin != "" {
+		c.startOnce.Do(func() {
+			_ = newCmd(c.bin, "start-server").Run()
+		})
+		nc, err = d.DialContext(ctx, "tcp", c.addr)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("%w: %v", ErrServerUnavailable, err)
+	}
+	cn := &conn{nc: nc, r: bufio.NewReader(nc)}
+	// Closing the socket is the only way to interrupt a blocked read.
+	cn.stop = context.AfterFunc(ctx, func() { nc.Close() })
+	return cn, nil
+}
+
+func (cn *conn) Close() error {
+	cn.stop()
+	return cn.nc.Close()
+}
+
+// send writes a length-prefixed request.
+func (cn *conn) send(req string) error {
+	_, err := fmt.Fprintf(cn.nc, "%04x%s", len(req), req)
+	return err
+}
+
+// readStatus reads an OKAY/FAIL status. A FAIL carries a message, which is
+// returned as a *ServerError.
+func (cn *conn) readStatus(req string) error {
+	var status [4]byte
+	if _, err := io.ReadFull(cn.r, status[:]); err != nil {
+		return fmt.Errorf("adb %s: read status: %w", req, err)
+	}
+	switch string(status[:]) {
+	case "OKAY":
+		return nil
+	case "FAIL":
+		msg, err := cn.readString()
+		if err != nil {
+			return fmt.Errorf("adb %s: read failure message: %w", req, err)
+		}
+		return &ServerError{Request: req, Message: msg}
+	default:
+		return fmt.Errorf("adb %s: unexpected status %q", req, status[:])
+	}
+}
+
+// readString reads a hex-length-prefixed string.
+func (cn *conn) readString() (string, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(cn.r, hdr[:]); err != nil {
+		return "", err
+	}
+	n, err := strconv.ParseUint(string(hdr[:]), 16, 32)
+	if err != nil {
+		return "", fmt.Errorf("bad length prefix %q", hdr[:])
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(cn.r, buf); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// request sends req and waits for OKAY.
+func (cn *conn) request(req string) error {
+	if err := cn.send(req); err != nil {
+		return fmt.Errorf("adb %s: %w", req, err)
+	}
+	return cn.readStatus(req)
+}
+
+// hostQuery runs a host request that answers with a single string,
+// such as host:devices-l or host:connect.
+func (c *Client) hostQuery(ctx context.Context, req string) (string, error) {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer cn.Close()
+	if err := cn.request(req); err != nil {
+		return "", c.ctxErr(ctx, err)
+	}
+	s, err := cn.readString()
+	if err != nil {
+		return "", c.ctxErr(ctx, fmt.Errorf("adb %s: %w", req, err))
+	}
+	return s, nil
+}
+
+// transport opens a connection routed to the device with the given serial.
+// The returned connection is ready for a single device service request.
+func (c *Client) transport(ctx context.Context, serial string) (*conn, error) {
+	cn, err := c.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := cn.request("host:transport:" + serial); err != nil {
+		cn.Close()
+		return nil, c.ctxErr(ctx, err)
+	}
+	return cn, nil
+}
+
+// service opens a device service (shell:, sync:, ...) on the given device.
+func (c *Client) service(ctx context.Context, serial, svc string) (*conn, error) {
+	cn, err := c.transport(ctx, serial)
+	if err != nil {
+		return nil, err
+	}
+	if err := cn.request(svc); err != nil {
+		cn.Close()
+		return nil, c.ctxErr(ctx, err)
+	}
+	return cn, nil
+}
+
+// ctxErr prefers the context's error when a read failed because the
+// connection was closed on cancellation.
+func (c *Client) ctxErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (cn *conn) readUint32() (uint32, error) {
+	var b [4]byte
+	if _, err := io.ReadFull(cn.r, b[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(b[:]), nil
+}
+
+// features returns the feature set a device advertises (shell_v2, stat_v2,
+// ls_v2, ...). Results are cached per transport serial.
+func (c *Client) features(ctx context.Context, serial string) map[string]bool {
+	c.mu.Lock()
+	f, ok := c.featCache[serial]
+	c.mu.Unlock()
+	if ok {
+		return f
+	}
+	f = make(map[string]bool)
+	out, err := c.hostQuery(ctx, "host-serial:"+serial+":features")
+	if err != nil {
+		return f // don't cache failures; the device may just be booting
+	}
+	for _, name := range strings.Split(strings.TrimSpace(out), ",") {
+		if name != "" {
+			f[name] = true
+		}
+	}
+	c.mu.Lock()
+	c.featCache[serial] = f
+	c.mu.Unlock()
+	return f
+}
+
+// shellQuote quotes s for the device's /system/bin/sh.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
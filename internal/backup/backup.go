@@ -0,0 +1,189 @@
+// Package backup keeps versioned copies of the manifest on destinations.
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+)
+
+const (
+	// Dir holds timestamped manifest backups on each destination, in a
+	// folder per machine.
+	Dir = ".fetchquest/backups"
+	// LegacyPath is the single backup written by older versions. It is
+	// still updated so they can restore from it.
+	LegacyPath = ".fetchquest/manifest.db"
+	// DefaultKeep is how many versions are kept per destination.
+	DefaultKeep = 10
+
+	timeFormat = "20060102-150405"
+)
+
+// Version is one manifest backup on a destination.
+type Version struct {
+	Machine string    // machine ID of the computer that wrote it
+	Path    string    // object path under the destination root
+	Time    time.Time // when the backup was taken (UTC)
+	Size    int64
+}
+
+// Snapshot writes a consistent copy of the manifest to a temp file and
+// returns its path. The caller removes it.
+func Snapshot(db *manifest.DB) (string, error) {
+	f, err := os.CreateTemp("", "fetchquest-manifest-*.db")
+	if err != nil {
+		return "", fmt.Errorf("snapshot manifest: %w", err)
+	}
+	f.Close()
+	if err := db.Snapshot(f.Name()); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("snapshot manifest: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// Upload stores snapshot on dest as a new version from machine taken at,
+// then deletes all but that machine's newest keep versions. It returns the
+// new version's path.
+func Upload(rc *rclone.Client, dest config.Destination, snapshot, machine string, at time.Time, keep int) (string, error) {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	name := path.Join(Dir, machine, "manifest-"+at.UTC().Format(timeFormat)+".db")
+	if err := rc.Copy(snapshot, dest.Remote(name)); err != nil {
+		return "", err
+	}
+	if err := rc.Copy(snapshot, dest.Remote(LegacyPath)); err != nil {
+		return name, err
+	}
+
+	versions, err := List(rc, dest)
+	if err != nil {
+		return name, err
+	}
+	var own []Version
+	for _, v := range versions {
+		if v.Machine == machine {
+			own = append(own, v)
+		}
+	}
+	for _, v := range own[min(keep, len(own)):] {
+		if err := rc.Delete(dest.Remote(v.Path)); err != nil {
+			return name, err
+		}
+	}
+	return name, nil
+}
+
+// List returns the manifest versions on dest, newest first.
+func List(rc *rclone.Client, dest config.Destination) ([]Version, error) {
+	objs, err := rc.List(dest.Remote(Dir), false)
+	if errors.Is(err, rclone.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []Version
+	for _, o := range objs {
+		// <machine>/manifest-<time>.db, or manifest-<time>.db from before
+		// backups were kept per machine.
+		machine, file := path.Split(o.Path)
+		machine = strings.TrimSuffix(machine, "/")
+		if strings.Contains(machine, "/") {
+			continue
+		}
+		stamp, ok := strings.CutPrefix(file, "manifest-")
+		if !ok || !strings.HasSuffix(stamp, ".db") {
+			continue
+		}
+		t, err := time.Parse(timeFormat, strings.TrimSuffix(stamp, ".db"))
+		if err != nil {
+			continue
+		}
+		versions = append(versions, Version{Machine: machine, Path: path.Join(Dir, o.Path), Time: t, Size: o.Size})
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Time.After(versions[j].Time) })
+	return versions, nil
+}
+
+// Merged is another machine's backup that was merged into the manifest.
+type Merged struct {
+	Dest    string
+	Version Version
+	Result  manifest.MergeResult
+}
+
+// MergeOthers merges the newest backup of every other machine found on
+// dests into db, so this machine's next backup includes their syncs.
+// Unreachable destinations and unusable backups are reported in err
+// without stopping the rest.
+func MergeOthers(rc *rclone.Client, db *manifest.DB, dests []config.Destination, machine string) ([]Merged, error) {
+	type located struct {
+		dest config.Destination
+		Version
+	}
+	var errs []error
+	newest := make(map[string]located)
+	for _, dest := range dests {
+		versions, err := List(rc, dest)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", dest.Name, err))
+			continue
+		}
+		for _, v := range versions {
+			if v.Machine == "" || v.Machine == machine {
+				continue
+			}
+			if cur, ok := newest[v.Machine]; !ok || v.Time.After(cur.Time) {
+				newest[v.Machine] = located{dest, v}
+			}
+		}
+	}
+
+	machines := make([]string, 0, len(newest))
+	for m := range newest {
+		machines = append(machines, m)
+	}
+	sort.Strings(machines)
+
+	var merged []Merged
+	for _, m := range machines {
+		l := newest[m]
+		res, err := MergeFrom(rc, db, l.dest, l.Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("merge %s from %s: %w", l.Path, l.dest.Name, err))
+			continue
+		}
+		merged = append(merged, Merged{Dest: l.dest.Name, Version: l.Version, Result: res})
+	}
+	return merged, errors.Join(errs...)
+}
+
+// MergeFrom downloads the backup at objectPath on dest and merges it into db.
+func MergeFrom(rc *rclone.Client, db *manifest.DB, dest config.Destination, objectPath string) (manifest.MergeResult, error) {
+	f, err := os.CreateTemp("", "fetchquest-merge-*.db")
+	if err != nil {
+		return manifest.MergeResult{}, err
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	if err := Download(rc, dest, objectPath, f.Name()); err != nil {
+		return manifest.MergeResult{}, err
+	}
+	return db.Merge(f.Name())
+}
+
+// Download fetches the backup at objectPath on dest to localPath.
+func Download(rc *rclone.Client, dest config.Destination, objectPath, localPath string) error {
+	return rc.CopyFrom(dest.Remote(objectPath), localPath)
+}
@@ -2,8 +2,12 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,12 +16,44 @@ import (
 type Destination struct {
 	Name         string `yaml:"name"`
 	RcloneRemote string `yaml:"rclone_remote"`
+	Layout       string `yaml:"layout,omitempty"` // path template for this destination; "" mirrors the local layout
+}
+
+// Remote returns the rclone path of objectPath (slash-separated, relative
+// to the destination root) on this destination.
+func (d Destination) Remote(objectPath string) string {
+	remote := d.RcloneRemote
+	if !strings.HasSuffix(remote, "/") {
+		remote += "/"
+	}
+	return remote + objectPath
 }
 
 // DeviceConfig stores per-device settings.
 type DeviceConfig struct {
-	Nickname string `yaml:"nickname,omitempty"`
-	WiFiIP   string `yaml:"wifi_ip,omitempty"`
+	Nickname   string `yaml:"nickname,omitempty"`
+	WiFiIP     string `yaml:"wifi_ip,omitempty"`
+	WiFiPort   int    `yaml:"wifi_port,omitempty"`   // wireless debugging port; 5555 if unset
+	PairedAddr string `yaml:"paired_addr,omitempty"` // endpoint used for `adb pair`
+}
+
+// defaultWiFiPort is the port adbd listens on after `adb tcpip`.
+const defaultWiFiPort = 5555
+
+// WiFiAddr returns the wireless ADB address (ip:port) for the device,
+// or "" if no WiFi IP is configured.
+func (d DeviceConfig) WiFiAddr() string {
+	if d.WiFiIP == "" {
+		return ""
+	}
+	if _, _, err := net.SplitHostPort(d.WiFiIP); err == nil {
+		return d.WiFiIP // already includes a port
+	}
+	port := d.WiFiPort
+	if port == 0 {
+		port = defaultWiFiPort
+	}
+	return net.JoinHostPort(d.WiFiIP, strconv.Itoa(port))
 }
 
 // Config is the top-level configuration.
@@ -28,8 +64,16 @@ type Config struct {
 	MediaPaths   []string                `yaml:"media_paths"`
 	AdbPath      string                  `yaml:"adb_path,omitempty"`
 	RclonePath   string                  `yaml:"rclone_path,omitempty"`
+	AutoSync     bool                    `yaml:"auto_sync,omitempty"`   // desktop app: sync when a Quest connects
+	MinBattery   int                     `yaml:"min_battery"`           // don't sync below this charge (%) unless charging; 0 disables
+	BackupKeep   int                     `yaml:"backup_keep,omitempty"` // manifest backups kept per destination; 0 means the default
+	Layout       string                  `yaml:"layout,omitempty"`      // path template for files under sync_dir; "" means DefaultLayout
 }
 
+// DefaultMinBattery is the lowest charge a headset that isn't plugged in
+// is synced at without asking.
+const DefaultMinBattery = 20
+
 // DefaultConfig returns a config with sensible defaults.
 func DefaultConfig() *Config {
 	home, _ := os.UserHomeDir()
@@ -39,7 +83,8 @@ func DefaultConfig() *Config {
 			"/sdcard/Oculus/VideoShots/",
 			"/sdcard/Oculus/Screenshots/",
 		},
-		Devices: make(map[string]DeviceConfig),
+		Devices:    make(map[string]DeviceConfig),
+		MinBattery: DefaultMinBattery,
 	}
 }
 
@@ -94,6 +139,18 @@ func Save(cfg *Config) error {
 	return nil
 }
 
+// WiFiAddrs returns the wireless ADB addresses of all configured devices.
+func (c *Config) WiFiAddrs() []string {
+	var addrs []string
+	for _, dc := range c.Devices {
+		if addr := dc.WiFiAddr(); addr != "" {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Strings(addrs)
+	return addrs
+}
+
 // ExpandSyncDir expands ~ in the sync dir path.
 func (c *Config) ExpandSyncDir() string {
 	if len(c.SyncDir) > 0 && c.SyncDir[0] == '~' {
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultLayout keeps each headset's files in its own folder, by media type.
+const DefaultLayout = "{device_nickname}/{type}/{name}"
+
+// LayoutFields are the placeholders a layout template may use.
+var LayoutFields = map[string]string{
+	"device_nickname": "headset nickname, or its serial if it has none",
+	"device_serial":   "headset serial",
+	"year":            "year the file was recorded (2025)",
+	"month":           "month the file was recorded (01-12)",
+	"day":             "day the file was recorded (01-31)",
+	"type":            "Videos, Screenshots, Photos or Other",
+	"app":             "name of the app the capture was taken in, or its package if the name isn't known",
+	"app_package":     "package of the app the capture was taken in (com.beatgames.beatsaber)",
+	"name":            "file name",
+}
+
+var layoutField = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// ValidateLayout checks that a path template only uses known placeholders
+// and ends in the file name.
+func ValidateLayout(template string) error {
+	if strings.TrimSpace(template) == "" {
+		return fmt.Errorf("layout is empty")
+	}
+	for _, m := range layoutField.FindAllStringSubmatch(template, -1) {
+		if _, ok := LayoutFields[m[1]]; !ok {
+			return fmt.Errorf("unknown placeholder {%s} in layout %q", m[1], template)
+		}
+	}
+	if !strings.HasSuffix(template, "{name}") {
+		return fmt.Errorf("layout %q must end with {name}", template)
+	}
+	return nil
+}
+
+// ExpandLayout fills in a template's placeholders from fields. Each
+// field value is used as a single path segment.
+func ExpandLayout(template string, fields map[string]string) string {
+	return layoutField.ReplaceAllStringFunc(template, func(m string) string {
+		if v, ok := fields[m[1:len(m)-1]]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// LocalLayout returns the template for paths under sync_dir.
+func (c *Config) LocalLayout() string {
+	if c.Layout == "" {
+		return DefaultLayout
+	}
+	return c.Layout
+}
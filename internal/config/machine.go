@@ -0,0 +1,47 @@
+package config
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var unsafeMachineChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// MachineID returns an identifier for this computer, creating it on first
+// use. It keeps manifest backups from different computers apart. The ID
+// is the hostname plus a random suffix, stored in the config dir so it
+// survives hostname changes.
+func MachineID() (string, error) {
+	path := filepath.Join(ConfigDir(), "machine_id")
+	if data, err := os.ReadFile(path); err == nil {
+		if id := strings.TrimSpace(string(data)); id != "" {
+			return id, nil
+		}
+	} else if !os.IsNotExist(err) {
+		return "", fmt.Errorf("read machine id: %w", err)
+	}
+
+	host, _ := os.Hostname()
+	host = strings.Trim(unsafeMachineChars.ReplaceAllString(strings.ToLower(host), "-"), "-")
+	if host == "" {
+		host = "machine"
+	}
+	var b [3]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", fmt.Errorf("create machine id: %w", err)
+	}
+	id := host + "-" + hex.EncodeToString(b[:])
+
+	if err := os.MkdirAll(ConfigDir(), 0o755); err != nil {
+		return "", fmt.Errorf("create config dir: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(id+"\n"), 0o644); err != nil {
+		return "", fmt.Errorf("write machine id: %w", err)
+	}
+	return id, nil
+}
@@ -0,0 +1,345 @@
+// Package discovery finds Quest headsets on the local network by browsing
+// the mDNS services adbd advertises when wireless ADB is enabled.
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// Service types advertised by adbd.
+const (
+	// TLSConnectService is advertised while wireless debugging is on
+	// (Android 11+); connecting requires the host to be paired first.
+	TLSConnectService = "_adb-tls-connect._tcp"
+	// LegacyService is advertised after `adb tcpip`.
+	LegacyService = "_adb._tcp"
+)
+
+// DefaultAddr is the mDNS IPv4 multicast group.
+const DefaultAddr = "224.0.0.251:5353"
+
+// DefaultTimeout is how long Browse listens for answers by default.
+const DefaultTimeout = 2 * time.Second
+
+// Service is an ADB endpoint found on the network.
+type Service struct {
+	Instance string // mDNS instance name, e.g. adb-1WMHH000000000-aBcDeF
+	Type     string // TLSConnectService or LegacyService
+	Serial   string // hardware serial parsed from the instance name, if any
+	Host     string
+	IP       string
+	Port     int
+}
+
+// Addr returns the ip:port to pass to `adb connect`.
+func (s Service) Addr() string {
+	return net.JoinHostPort(s.IP, strconv.Itoa(s.Port))
+}
+
+// Browser sends mDNS queries and collects the answers.
+type Browser struct {
+	// Addr is where queries are sent. Defaults to DefaultAddr; point it at
+	// a responder on 127.0.0.1 to browse without touching the network.
+	Addr string
+	// Timeout bounds how long to wait for answers. Defaults to DefaultTimeout.
+	Timeout time.Duration
+}
+
+// Browse queries both adbd service types using a default Browser.
+func Browse(ctx context.Context) ([]Service, error) {
+	return (&Browser{}).Browse(ctx, TLSConnectService, LegacyService)
+}
+
+// Browse queries for the given service types and returns every instance
+// that resolved to an address before the timeout. Queries are sent from an
+// ephemeral port, so responders answer by unicast (RFC 6762 §6.7) and no
+// access to port 5353 is needed.
+func (b *Browser) Browse(ctx context.Context, types ...string) ([]Service, error) {
+	addr := b.Addr
+	if addr == "" {
+		addr = DefaultAddr
+	}
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	raddr, err := net.ResolveUDPAddr("udp4", addr)
+	if err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", addr, err)
+	}
+	query, err := buildQuery(types)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("open mDNS socket: %w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	stop := context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })
+	defer stop()
+
+	// Send the query twice; a single UDP packet is easily lost on WiFi.
+	go func() {
+		for i := 0; i < 2; i++ {
+			if _, err := conn.WriteToUDP(query, raddr); err != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(timeout / 3):
+			}
+		}
+	}()
+
+	rs := newRecordSet()
+	buf := make([]byte, 9000)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return nil, fmt.Errorf("read mDNS answer: %w", err)
+		}
+		rs.add(buf[:n]) // ignore malformed packets
+	}
+	return rs.services(types), nil
+}
+
+func buildQuery(types []string) ([]byte, error) {
+	b := dnsmessage.NewBuilder(nil, dnsmessage.Header{})
+	if err := b.StartQuestions(); err != nil {
+		return nil, err
+	}
+	for _, t := range types {
+		name, err := dnsmessage.NewName(t + ".local.")
+		if err != nil {
+			return nil, fmt.Errorf("service name %q: %w", t, err)
+		}
+		err = b.Question(dnsmessage.Question{
+			Name:  name,
+			Type:  dnsmessage.TypePTR,
+			Class: dnsmessage.ClassINET | 1<<15, // QU: ask for a unicast reply
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return b.Finish()
+}
+
+type srvTarget struct {
+	host string
+	port int
+}
+
+// recordSet accumulates answers across packets; responders often spread
+// the PTR, SRV and A records over several of them.
+type recordSet struct {
+	ptr map[string][]string // service type -> instance names
+	srv map[string]srvTarget
+	a   map[string]string // host -> IPv4
+}
+
+func newRecordSet() *recordSet {
+	return &recordSet{
+		ptr: make(map[string][]string),
+		srv: make(map[string]srvTarget),
+		a:   make(map[string]string),
+	}
+}
+
+func (rs *recordSet) add(pkt []byte) {
+	var p dnsmessage.Parser
+	hdr, err := p.Start(pkt)
+	if err != nil || !hdr.Response {
+		return
+	}
+	if err := p.SkipAllQuestions(); err != nil {
+		return
+	}
+	for {
+		h, err := p.AnswerHeader()
+		if err == dnsmessage.ErrSectionDone {
+			break
+		}
+		if err != nil {
+			return
+		}
+		rs.record(&p, h, p.SkipAnswer)
+	}
+	// SRV and A records usually come as additionals.
+	if err := p.SkipAllAuthorities(); err != nil {
+		return
+	}
+	for {
+		h, err := p.AdditionalHeader()
+		if err != nil {
+			return
+		}
+		rs.record(&p, h, p.SkipAdditional)
+	}
+}
+
+// record reads the body of the resource whose header was just parsed.
+// skip discards it if it isn't a type we use.
+func (rs *recordSet) record(p *dnsmessage.Parser, h dnsmessage.ResourceHeader, skip func() error) {
+	name := strings.TrimSuffix(strings.ToLower(h.Name.String()), ".")
+	switch h.Type {
+	case dnsmessage.TypePTR:
+		r, err := p.PTRResource()
+		if err != nil {
+			return
+		}
+		inst := strings.TrimSuffix(r.PTR.String(), ".")
+		for _, have := range rs.ptr[name] {
+			if have == inst {
+				return
+			}
+		}
+		rs.ptr[name] = append(rs.ptr[name], inst)
+	case dnsmessage.TypeSRV:
+		r, err := p.SRVResource()
+		if err != nil {
+			return
+		}
+		rs.srv[name] = srvTarget{
+			host: strings.TrimSuffix(strings.ToLower(r.Target.String()), "."),
+			port: int(r.Port),
+		}
+	case dnsmessage.TypeA:
+		r, err := p.AResource()
+		if err != nil {
+			return
+		}
+		rs.a[name] = net.IP(r.A[:]).String()
+	default:
+		_ = skip()
+	}
+}
+
+func (rs *recordSet) services(types []string) []Service {
+	var out []Service
+	for _, t := range types {
+		for _, inst := range rs.ptr[t+".local"] {
+			target, ok := rs.srv[strings.ToLower(inst)]
+			if !ok {
+				continue
+			}
+			ip, ok := rs.a[target.host]
+			if !ok {
+				continue
+			}
+			label := strings.TrimSuffix(inst, "."+t+".local")
+			out = append(out, Service{
+				Instance: label,
+				Type:     t,
+				Serial:   serialFromInstance(label),
+				Host:     target.host,
+				IP:       ip,
+				Port:     target.port,
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Serial != out[j].Serial {
+			return out[i].Serial < out[j].Serial
+		}
+		return out[i].Type == TLSConnectService && out[j].Type != TLSConnectService
+	})
+	return out
+}
+
+// serialFromInstance extracts the hardware serial from an adbd instance
+// name. adbd names itself adb-<ro.serialno>, with a random suffix added
+// for the TLS service.
+func serialFromInstance(label string) string {
+	s, ok := strings.CutPrefix(label, "adb-")
+	if !ok {
+		return ""
+	}
+	if i := strings.LastIndexByte(s, '-'); i > 0 {
+		s = s[:i]
+	}
+	return s
+}
+
+// UpdateConfig points the saved WiFi address of each known headset at the
+// address it was just found on, so DHCP changes don't break reconnecting.
+// Only headsets already set up for wireless ADB are touched, and the TLS
+// service is only used for headsets that have been paired.
+// It returns the serials whose address changed.
+func UpdateConfig(cfg *config.Config, svcs []Service) []string {
+	var changed []string
+	done := make(map[string]bool)
+	for _, s := range svcs {
+		if s.Serial == "" || done[s.Serial] {
+			continue
+		}
+		dc, ok := cfg.Devices[s.Serial]
+		if !ok || (dc.WiFiIP == "" && dc.PairedAddr == "") {
+			continue
+		}
+		if s.Type == TLSConnectService && dc.PairedAddr == "" {
+			continue
+		}
+		done[s.Serial] = true
+		if dc.WiFiAddr() == s.Addr() {
+			continue
+		}
+		dc.WiFiIP = s.IP
+		dc.WiFiPort = s.Port
+		cfg.Devices[s.Serial] = dc
+		changed = append(changed, s.Serial)
+	}
+	return changed
+}
+
+// ConnectConfigured connects to the WiFi address of every configured
+// headset. If some can't be reached, it browses the network and retries
+// those that turned up under a new address. It returns the addresses that
+// still failed and whether cfg was updated and needs saving.
+func ConnectConfigured(ctx context.Context, client *adb.Client, cfg *config.Config) (map[string]error, bool) {
+	errs := client.ConnectAll(cfg.WiFiAddrs())
+	if len(errs) == 0 {
+		return errs, false
+	}
+	svcs, err := Browse(ctx)
+	if err != nil {
+		return errs, false
+	}
+	old := make(map[string]string)
+	for serial, dc := range cfg.Devices {
+		old[serial] = dc.WiFiAddr()
+	}
+	changed := UpdateConfig(cfg, svcs)
+	if len(changed) == 0 {
+		return errs, false
+	}
+	var retry []string
+	for _, serial := range changed {
+		delete(errs, old[serial])
+		retry = append(retry, cfg.Devices[serial].WiFiAddr())
+	}
+	for addr, err := range client.ConnectAll(retry) {
+		errs[addr] = err
+	}
+	return errs, true
+}
@@ -0,0 +1,164 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// fakeService is an adbd endpoint advertised by the loopback responder.
+type fakeService struct {
+	instance, typ, host string
+	ip                  [4]byte
+	port                uint16
+}
+
+var headsets = []fakeService{
+	{"adb-1WMHH000000001-aBcDeF", TLSConnectService, "Android-1.local", [4]byte{192, 168, 1, 42}, 37215},
+	{"adb-1WMHH000000001", LegacyService, "Android-1.local", [4]byte{192, 168, 1, 42}, 5555},
+	{"adb-1WMHH000000002", LegacyService, "Android-2.local", [4]byte{192, 168, 1, 43}, 5555},
+	{"adb-1WMHH000000003", LegacyService, "Android-3.local", [4]byte{192, 168, 1, 44}, 5555},
+}
+
+// respond answers mDNS PTR queries on a 127.0.0.1 socket, the way adbd's
+// responder does: the PTR as an answer, and the SRV and A records in a
+// separate packet as additionals.
+func respond(t *testing.T, svcs []fakeService) string {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	go func() {
+		buf := make([]byte, 9000)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			var p dnsmessage.Parser
+			if _, err := p.Start(buf[:n]); err != nil {
+				continue
+			}
+			qs, err := p.AllQuestions()
+			if err != nil {
+				continue
+			}
+			for _, q := range qs {
+				for _, s := range svcs {
+					if q.Type != dnsmessage.TypePTR || q.Name.String() != s.typ+".local." {
+						continue
+					}
+					for _, pkt := range answer(t, s) {
+						conn.WriteToUDP(pkt, from)
+					}
+				}
+			}
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func answer(t *testing.T, s fakeService) [][]byte {
+	t.Helper()
+	svc := dnsmessage.MustNewName(s.typ + ".local.")
+	inst := dnsmessage.MustNewName(s.instance + "." + s.typ + ".local.")
+	host := dnsmessage.MustNewName(s.host + ".")
+	hdr := dnsmessage.Header{Response: true, Authoritative: true}
+
+	ptr := dnsmessage.NewBuilder(nil, hdr)
+	ptr.StartAnswers()
+	ptr.PTRResource(dnsmessage.ResourceHeader{Name: svc, Class: dnsmessage.ClassINET, TTL: 120},
+		dnsmessage.PTRResource{PTR: inst})
+	first, err := ptr.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extra := dnsmessage.NewBuilder(nil, hdr)
+	extra.StartAdditionals()
+	extra.SRVResource(dnsmessage.ResourceHeader{Name: inst, Class: dnsmessage.ClassINET, TTL: 120},
+		dnsmessage.SRVResource{Target: host, Port: s.port})
+	extra.AResource(dnsmessage.ResourceHeader{Name: host, Class: dnsmessage.ClassINET, TTL: 120},
+		dnsmessage.AResource{A: s.ip})
+	second, err := extra.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return [][]byte{first, second}
+}
+
+func TestBrowse(t *testing.T) {
+	b := &Browser{Addr: respond(t, headsets), Timeout: 300 * time.Millisecond}
+	svcs, err := b.Browse(context.Background(), TLSConnectService, LegacyService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range svcs {
+		got = append(got, s.Serial+" "+s.Type+" "+s.Addr())
+	}
+	want := []string{
+		"1WMHH000000001 " + TLSConnectService + " 192.168.1.42:37215",
+		"1WMHH000000001 " + LegacyService + " 192.168.1.42:5555",
+		"1WMHH000000002 " + LegacyService + " 192.168.1.43:5555",
+		"1WMHH000000003 " + LegacyService + " 192.168.1.44:5555",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Browse =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if svcs[0].Instance != "adb-1WMHH000000001-aBcDeF" || svcs[0].Host != "android-1.local" {
+		t.Errorf("first service = %+v", svcs[0])
+	}
+
+	legacy, err := b.Browse(context.Background(), LegacyService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(legacy) != 3 {
+		t.Errorf("browsing %s found %d services, want 3", LegacyService, len(legacy))
+	}
+}
+
+func TestUpdateConfig(t *testing.T) {
+	b := &Browser{Addr: respond(t, headsets), Timeout: 300 * time.Millisecond}
+	svcs, err := b.Browse(context.Background(), TLSConnectService, LegacyService)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{Devices: map[string]config.DeviceConfig{
+		// Paired for wireless debugging: follows the TLS service.
+		"1WMHH000000001": {Nickname: "Lab", WiFiIP: "192.168.1.10", WiFiPort: 41000, PairedAddr: "192.168.1.10:39999"},
+		// Set up with `adb tcpip`: follows the legacy service.
+		"1WMHH000000002": {WiFiIP: "192.168.1.11"},
+		// Known, but only over USB: left alone.
+		"1WMHH000000003": {Nickname: "Desk"},
+	}}
+
+	changed := UpdateConfig(cfg, svcs)
+	slices.Sort(changed)
+	if want := []string{"1WMHH000000001", "1WMHH000000002"}; !slices.Equal(changed, want) {
+		t.Errorf("changed = %v, want %v", changed, want)
+	}
+	if dc := cfg.Devices["1WMHH000000001"]; dc.WiFiIP != "192.168.1.42" || dc.WiFiPort != 37215 || dc.Nickname != "Lab" {
+		t.Errorf("paired headset = %+v", dc)
+	}
+	if dc := cfg.Devices["1WMHH000000002"]; dc.WiFiIP != "192.168.1.43" || dc.WiFiPort != 5555 {
+		t.Errorf("tcpip headset = %+v", dc)
+	}
+	if dc := cfg.Devices["1WMHH000000003"]; dc.WiFiIP != "" || dc.WiFiPort != 0 {
+		t.Errorf("USB-only headset = %+v", dc)
+	}
+
+	if again := UpdateConfig(cfg, svcs); len(again) != 0 {
+		t.Errorf("second UpdateConfig changed %v", again)
+	}
+}
@@ -0,0 +1,90 @@
+package manifest
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Where an app label came from.
+const (
+	LabelFromHeadset = "headset"
+	LabelFromUser    = "user"
+)
+
+// App is an app captures were taken in, with its cached label.
+type App struct {
+	Package   string
+	Label     string // "" if not known
+	Source    string // LabelFromHeadset or LabelFromUser
+	UpdatedAt *time.Time
+	Files     int // captures in the manifest
+}
+
+// Apps returns every app that captures in the manifest were taken in, and
+// every app with a label, by package name.
+func (m *DB) Apps() ([]App, error) {
+	rows, err := m.db.Query(
+		`SELECT p.package, COALESCE(al.label, ''), COALESCE(al.source, ''), al.updated_at,
+		        (SELECT COUNT(*) FROM files f WHERE f.app = p.package)
+		 FROM (SELECT app AS package FROM files WHERE app != '' UNION SELECT package FROM app_labels) p
+		 LEFT JOIN app_labels al ON al.package = p.package
+		 ORDER BY p.package`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list apps: %w", err)
+	}
+	defer rows.Close()
+	var apps []App
+	for rows.Next() {
+		var a App
+		var updated sql.NullTime
+		if err := rows.Scan(&a.Package, &a.Label, &a.Source, &updated, &a.Files); err != nil {
+			return nil, fmt.Errorf("list apps: %w", err)
+		}
+		if updated.Valid {
+			a.UpdatedAt = &updated.Time
+		}
+		apps = append(apps, a)
+	}
+	return apps, rows.Err()
+}
+
+// AppLabel returns the cached label for a package, or "".
+func (m *DB) AppLabel(pkg string) (string, error) {
+	var label string
+	err := m.db.QueryRow(`SELECT label FROM app_labels WHERE package = ?`, pkg).Scan(&label)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("app label: %w", err)
+	}
+	return label, nil
+}
+
+// SetAppLabel caches a package's label. A label from a headset never
+// replaces one the user set.
+func (m *DB) SetAppLabel(pkg, label, source string) error {
+	_, err := m.db.Exec(
+		`INSERT INTO app_labels (package, label, source, updated_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT(package) DO UPDATE SET
+		   label = excluded.label,
+		   source = excluded.source,
+		   updated_at = excluded.updated_at
+		 WHERE app_labels.source != ? OR excluded.source = ?`,
+		pkg, label, source, time.Now(), LabelFromUser, LabelFromUser,
+	)
+	if err != nil {
+		return fmt.Errorf("set app label: %w", err)
+	}
+	return nil
+}
+
+// DeleteAppLabel forgets a package's label.
+func (m *DB) DeleteAppLabel(pkg string) error {
+	if _, err := m.db.Exec(`DELETE FROM app_labels WHERE package = ?`, pkg); err != nil {
+		return fmt.Errorf("delete app label: %w", err)
+	}
+	return nil
+}
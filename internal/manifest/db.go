@@ -47,35 +47,3 @@ func (m *DB) Close() error {
 func (m *DB) Path() string {
 	return m.path
 }
-
-func (m *DB) migrate() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS files (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		device_serial TEXT NOT NULL,
-		remote_path TEXT NOT NULL,
-		local_path TEXT NOT NULL DEFAULT '',
-		size INTEGER NOT NULL DEFAULT 0,
-		mtime INTEGER NOT NULL DEFAULT 0,
-		sha256 TEXT NOT NULL DEFAULT '',
-		pulled_at DATETIME,
-		UNIQUE(device_serial, remote_path)
-	);
-
-	CREATE TABLE IF NOT EXISTS dest_syncs (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		file_id INTEGER NOT NULL,
-		destination TEXT NOT NULL,
-		synced_at DATETIME NOT NULL,
-		FOREIGN KEY (file_id) REFERENCES files(id),
-		UNIQUE(file_id, destination)
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_files_device ON files(device_serial);
-	CREATE INDEX IF NOT EXISTS idx_dest_syncs_file ON dest_syncs(file_id);
-	`
-	if _, err := m.db.Exec(schema); err != nil {
-		return fmt.Errorf("migrate: %w", err)
-	}
-	return nil
-}
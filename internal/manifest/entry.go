@@ -3,7 +3,11 @@ package manifest
 import (
 	"database/sql"
 	"fmt"
+	"path"
+	"strings"
 	"time"
+
+	"github.com/FluidXR/fetchquest/internal/media"
 )
 
 // Entry represents a synced file in the manifest.
@@ -16,6 +20,44 @@ type Entry struct {
 	MTime        int64
 	SHA256       string
 	PulledAt     *time.Time
+	Media
+	App      string // package of the app the capture was taken in, "" if unknown
+	AppLabel string // the app's name, where a headset has told us
+}
+
+// Media is capture metadata read from the file itself. Zero values mean
+// unknown, as for files that were never probed.
+type Media struct {
+	CapturedAt int64 // unix seconds
+	DurationMS int64
+	Width      int
+	Height     int
+	Codec      string
+}
+
+// IsZero reports whether nothing is known.
+func (md Media) IsZero() bool {
+	return md == Media{}
+}
+
+// CaptureTime is when the file was recorded if that is known, otherwise
+// its modification time on the headset.
+func (e Entry) CaptureTime() time.Time {
+	if e.CapturedAt != 0 {
+		return time.Unix(e.CapturedAt, 0)
+	}
+	return time.Unix(e.MTime, 0)
+}
+
+// ImportScheme prefixes the remote path of files imported from a folder
+// that weren't found on any headset. They are keyed by hash:
+// import://<sha256>/<name>.
+const ImportScheme = "import://"
+
+// IsImported reports whether remotePath is an imported file rather than a
+// path on a headset.
+func IsImported(remotePath string) bool {
+	return strings.HasPrefix(remotePath, ImportScheme)
 }
 
 // DestSync records that a file has been synced to a destination.
@@ -24,6 +66,11 @@ type DestSync struct {
 	FileID      int64
 	Destination string
 	SyncedAt    time.Time
+	ObjectPath  string // path under the destination root, as uploaded
+	Size        int64  // size of the uploaded object
+	SHA256      string // hash confirmed at the destination, "" if it couldn't be checked
+	ObjectID    string // backend object ID, where the backend has one
+	VerifiedAt  *time.Time
 }
 
 // IsPulled returns true if the file has been pulled (device_serial, remote_path, size, mtime match).
@@ -39,18 +86,31 @@ func (m *DB) IsPulled(deviceSerial, remotePath string, size, mtime int64) (bool,
 	return count > 0, nil
 }
 
-// RecordPull inserts or updates a file entry after pulling.
-func (m *DB) RecordPull(deviceSerial, remotePath, localPath string, size, mtime int64) (int64, error) {
+// HasHash reports whether any file in the manifest has the given SHA-256.
+func (m *DB) HasHash(sha256 string) (bool, error) {
+	var count int
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM files WHERE sha256 = ?`, sha256).Scan(&count); err != nil {
+		return false, fmt.Errorf("check hash: %w", err)
+	}
+	return count > 0, nil
+}
+
+// RecordPull inserts or updates a file entry after pulling. sha256 is the
+// verified hash of the pulled file. The app is taken from the file name.
+func (m *DB) RecordPull(deviceSerial, remotePath, localPath string, size, mtime int64, sha256 string) (int64, error) {
 	now := time.Now()
+	app, _, _ := media.ParseName(path.Base(remotePath))
 	res, err := m.db.Exec(
-		`INSERT INTO files (device_serial, remote_path, local_path, size, mtime, pulled_at)
-		 VALUES (?, ?, ?, ?, ?, ?)
+		`INSERT INTO files (device_serial, remote_path, local_path, size, mtime, sha256, pulled_at, app)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		 ON CONFLICT(device_serial, remote_path) DO UPDATE SET
 		   local_path = excluded.local_path,
 		   size = excluded.size,
 		   mtime = excluded.mtime,
-		   pulled_at = excluded.pulled_at`,
-		deviceSerial, remotePath, localPath, size, mtime, now,
+		   sha256 = excluded.sha256,
+		   pulled_at = excluded.pulled_at,
+		   app = excluded.app`,
+		deviceSerial, remotePath, localPath, size, mtime, sha256, now, app,
 	)
 	if err != nil {
 		return 0, fmt.Errorf("record pull: %w", err)
@@ -63,13 +123,42 @@ func (m *DB) RecordPull(deviceSerial, remotePath, localPath string, size, mtime
 	return id, nil
 }
 
-// RecordDestSync marks a file as synced to a destination.
-func (m *DB) RecordDestSync(fileID int64, destination string) error {
+// RecordMedia stores the capture metadata read from a file.
+func (m *DB) RecordMedia(fileID int64, md Media) error {
 	_, err := m.db.Exec(
-		`INSERT INTO dest_syncs (file_id, destination, synced_at)
-		 VALUES (?, ?, ?)
-		 ON CONFLICT(file_id, destination) DO UPDATE SET synced_at = excluded.synced_at`,
-		fileID, destination, time.Now(),
+		`UPDATE files SET captured_at = ?, duration_ms = ?, width = ?, height = ?, codec = ? WHERE id = ?`,
+		md.CapturedAt, md.DurationMS, md.Width, md.Height, md.Codec, fileID,
+	)
+	if err != nil {
+		return fmt.Errorf("record media: %w", err)
+	}
+	return nil
+}
+
+// RecordHash stores the SHA-256 of a file pulled before hashes were
+// recorded, taken from its local copy.
+func (m *DB) RecordHash(fileID int64, sha256 string) error {
+	if _, err := m.db.Exec(`UPDATE files SET sha256 = ? WHERE id = ?`, sha256, fileID); err != nil {
+		return fmt.Errorf("record hash: %w", err)
+	}
+	return nil
+}
+
+// RecordDestSync marks a file as synced to a destination once the upload
+// has been verified there, with what was actually written.
+func (m *DB) RecordDestSync(ds DestSync) error {
+	now := time.Now()
+	_, err := m.db.Exec(
+		`INSERT INTO dest_syncs (file_id, destination, synced_at, object_path, size, sha256, object_id, verified_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(file_id, destination) DO UPDATE SET
+		   synced_at = excluded.synced_at,
+		   object_path = excluded.object_path,
+		   size = excluded.size,
+		   sha256 = excluded.sha256,
+		   object_id = excluded.object_id,
+		   verified_at = excluded.verified_at`,
+		ds.FileID, ds.Destination, now, ds.ObjectPath, ds.Size, ds.SHA256, ds.ObjectID, now,
 	)
 	if err != nil {
 		return fmt.Errorf("record dest sync: %w", err)
@@ -77,7 +166,161 @@ func (m *DB) RecordDestSync(fileID int64, destination string) error {
 	return nil
 }
 
-// IsFullySynced checks if a file has been synced to all given destinations.
+// RecordFound records a file found already uploaded to a destination, as
+// when rebuilding a lost manifest. An existing files row is left alone;
+// otherwise one is created from e. ds.FileID is ignored, and the sync is
+// only verified if ds.VerifiedAt is set.
+func (m *DB) RecordFound(e Entry, ds DestSync) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("record found: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	if _, err := tx.Exec(
+		`INSERT INTO files (device_serial, remote_path, local_path, size, mtime, sha256, pulled_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(device_serial, remote_path) DO NOTHING`,
+		e.DeviceSerial, e.RemotePath, e.LocalPath, e.Size, e.MTime, e.SHA256, now,
+	); err != nil {
+		return fmt.Errorf("record found: %w", err)
+	}
+	var fileID int64
+	if err := tx.QueryRow(
+		`SELECT id FROM files WHERE device_serial = ? AND remote_path = ?`, e.DeviceSerial, e.RemotePath,
+	).Scan(&fileID); err != nil {
+		return fmt.Errorf("record found: %w", err)
+	}
+	if _, err := tx.Exec(
+		`INSERT INTO dest_syncs (file_id, destination, synced_at, object_path, size, sha256, object_id, verified_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(file_id, destination) DO UPDATE SET
+		   object_path = excluded.object_path,
+		   size = excluded.size,
+		   sha256 = excluded.sha256,
+		   object_id = excluded.object_id,
+		   verified_at = excluded.verified_at`,
+		fileID, ds.Destination, now, ds.ObjectPath, ds.Size, ds.SHA256, ds.ObjectID, ds.VerifiedAt,
+	); err != nil {
+		return fmt.Errorf("record found: %w", err)
+	}
+	return tx.Commit()
+}
+
+// MarkVerified records that an existing sync has been checked at the
+// destination, along with what was found there.
+func (m *DB) MarkVerified(ds DestSync) error {
+	_, err := m.db.Exec(
+		`UPDATE dest_syncs SET verified_at = ?, object_path = ?, size = ?, sha256 = ?, object_id = ?
+		 WHERE file_id = ? AND destination = ?`,
+		time.Now(), ds.ObjectPath, ds.Size, ds.SHA256, ds.ObjectID, ds.FileID, ds.Destination,
+	)
+	if err != nil {
+		return fmt.Errorf("mark verified: %w", err)
+	}
+	return nil
+}
+
+// DeleteDestSync forgets that a file was synced to a destination, so the
+// next push uploads it again.
+func (m *DB) DeleteDestSync(fileID int64, destination string) error {
+	_, err := m.db.Exec(`DELETE FROM dest_syncs WHERE file_id = ? AND destination = ?`, fileID, destination)
+	if err != nil {
+		return fmt.Errorf("delete dest sync: %w", err)
+	}
+	return nil
+}
+
+// UpdateLocalPath records that a file's local copy has moved.
+func (m *DB) UpdateLocalPath(fileID int64, localPath string) error {
+	if _, err := m.db.Exec(`UPDATE files SET local_path = ? WHERE id = ?`, localPath, fileID); err != nil {
+		return fmt.Errorf("update local path: %w", err)
+	}
+	return nil
+}
+
+// UpdateObjectPath records that a file's copy on destination has moved.
+func (m *DB) UpdateObjectPath(fileID int64, destination, objectPath string) error {
+	_, err := m.db.Exec(
+		`UPDATE dest_syncs SET object_path = ? WHERE file_id = ? AND destination = ?`,
+		objectPath, fileID, destination,
+	)
+	if err != nil {
+		return fmt.Errorf("update object path: %w", err)
+	}
+	return nil
+}
+
+// ObjectOwner returns the ID of the file recorded at objectPath on
+// destination, or 0 if there is none.
+func (m *DB) ObjectOwner(destination, objectPath string) (int64, error) {
+	var id int64
+	err := m.db.QueryRow(
+		`SELECT file_id FROM dest_syncs WHERE destination = ? AND object_path = ? LIMIT 1`,
+		destination, objectPath,
+	).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("object owner: %w", err)
+	}
+	return id, nil
+}
+
+// DeleteFile forgets a file and its syncs, so it is pulled again if it is
+// still on the headset.
+func (m *DB) DeleteFile(fileID int64) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM dest_syncs WHERE file_id = ?`, fileID); err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM relayout_moves WHERE file_id = ?`, fileID); err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM files WHERE id = ?`, fileID); err != nil {
+		return fmt.Errorf("delete file: %w", err)
+	}
+	return tx.Commit()
+}
+
+// GetUnverifiedFiles returns files recorded as synced to destination whose
+// upload was never verified there (synced before verification existed).
+func (m *DB) GetUnverifiedFiles(destination string) ([]SyncedEntry, error) {
+	return m.querySynced(`ds.destination = ? AND ds.verified_at IS NULL`, destination)
+}
+
+// IsHashVerified reports whether every given destination has confirmed a
+// copy whose SHA-256 matches the one taken on the headset.
+func (m *DB) IsHashVerified(fileID int64, destinations []string) (bool, error) {
+	if len(destinations) == 0 {
+		return false, nil
+	}
+	args := []interface{}{fileID}
+	for _, d := range destinations {
+		args = append(args, d)
+	}
+	var count int
+	err := m.db.QueryRow(
+		`SELECT COUNT(DISTINCT ds.destination) FROM dest_syncs ds
+		 JOIN files f ON f.id = ds.file_id
+		 WHERE ds.file_id = ? AND f.sha256 != '' AND ds.sha256 = f.sha256
+		   AND ds.destination IN (`+placeholders(len(destinations))+`)`,
+		args...,
+	).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("check hash verified: %w", err)
+	}
+	return count == len(destinations), nil
+}
+
+// IsFullySynced checks if a file has been synced to, and verified at, all
+// given destinations.
 func (m *DB) IsFullySynced(deviceSerial, remotePath string, destinations []string) (bool, error) {
 	if len(destinations) == 0 {
 		return false, nil
@@ -86,9 +329,16 @@ func (m *DB) IsFullySynced(deviceSerial, remotePath string, destinations []strin
 	if err != nil {
 		return false, nil // file not in manifest
 	}
+	args := []interface{}{fileID}
+	for _, d := range destinations {
+		args = append(args, d)
+	}
 	var count int
 	err = m.db.QueryRow(
-		`SELECT COUNT(DISTINCT destination) FROM dest_syncs WHERE file_id = ?`, fileID,
+		`SELECT COUNT(DISTINCT destination) FROM dest_syncs
+		 WHERE file_id = ? AND verified_at IS NOT NULL
+		   AND destination IN (`+placeholders(len(destinations))+`)`,
+		args...,
 	).Scan(&count)
 	if err != nil {
 		return false, fmt.Errorf("check synced: %w", err)
@@ -115,8 +365,10 @@ func (m *DB) getFileID(deviceSerial, remotePath string) (int64, error) {
 // GetUnpushedFiles returns files that have been pulled but not synced to the given destination.
 func (m *DB) GetUnpushedFiles(destination string) ([]Entry, error) {
 	rows, err := m.db.Query(
-		`SELECT f.id, f.device_serial, f.remote_path, f.local_path, f.size, f.mtime, f.sha256
+		`SELECT f.id, f.device_serial, f.remote_path, f.local_path, f.size, f.mtime, f.sha256,
+		        f.captured_at, f.duration_ms, f.width, f.height, f.codec, f.app, COALESCE(al.label, '')
 		 FROM files f
+		 LEFT JOIN app_labels al ON al.package = f.app
 		 WHERE f.pulled_at IS NOT NULL
 		   AND f.id NOT IN (SELECT file_id FROM dest_syncs WHERE destination = ?)`,
 		destination,
@@ -129,7 +381,8 @@ func (m *DB) GetUnpushedFiles(destination string) ([]Entry, error) {
 	var entries []Entry
 	for rows.Next() {
 		var e Entry
-		if err := rows.Scan(&e.ID, &e.DeviceSerial, &e.RemotePath, &e.LocalPath, &e.Size, &e.MTime, &e.SHA256); err != nil {
+		if err := rows.Scan(&e.ID, &e.DeviceSerial, &e.RemotePath, &e.LocalPath, &e.Size, &e.MTime, &e.SHA256,
+			&e.CapturedAt, &e.DurationMS, &e.Width, &e.Height, &e.Codec, &e.App, &e.AppLabel); err != nil {
 			return nil, fmt.Errorf("scan unpushed: %w", err)
 		}
 		entries = append(entries, e)
@@ -137,17 +390,141 @@ func (m *DB) GetUnpushedFiles(destination string) ([]Entry, error) {
 	return entries, rows.Err()
 }
 
-// GetFullySyncedFiles returns files synced to ALL destinations for a device (safe to clean).
+// SyncedEntry is a file together with its sync record for one destination.
+type SyncedEntry struct {
+	Entry
+	Sync DestSync
+}
+
+// GetDestSyncedFiles returns every file recorded as synced to destination.
+func (m *DB) GetDestSyncedFiles(destination string) ([]SyncedEntry, error) {
+	return m.querySynced(`ds.destination = ?`, destination)
+}
+
+func (m *DB) querySynced(where string, args ...interface{}) ([]SyncedEntry, error) {
+	rows, err := m.db.Query(
+		`SELECT f.id, f.device_serial, f.remote_path, f.local_path, f.size, f.mtime, f.sha256,
+		        f.captured_at, f.duration_ms, f.width, f.height, f.codec, f.app, COALESCE(al.label, ''),
+		        ds.id, ds.destination, ds.synced_at, ds.object_path, ds.size, ds.sha256, ds.object_id, ds.verified_at
+		 FROM files f
+		 JOIN dest_syncs ds ON ds.file_id = f.id
+		 LEFT JOIN app_labels al ON al.package = f.app
+		 WHERE `+where+`
+		 ORDER BY f.id`,
+		args...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get synced files: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []SyncedEntry
+	for rows.Next() {
+		var e SyncedEntry
+		var verifiedAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.DeviceSerial, &e.RemotePath, &e.LocalPath, &e.Size, &e.MTime, &e.SHA256,
+			&e.CapturedAt, &e.DurationMS, &e.Width, &e.Height, &e.Codec, &e.App, &e.AppLabel,
+			&e.Sync.ID, &e.Sync.Destination, &e.Sync.SyncedAt, &e.Sync.ObjectPath, &e.Sync.Size,
+			&e.Sync.SHA256, &e.Sync.ObjectID, &verifiedAt); err != nil {
+			return nil, fmt.Errorf("scan synced file: %w", err)
+		}
+		e.Sync.FileID = e.ID
+		if verifiedAt.Valid {
+			e.Sync.VerifiedAt = &verifiedAt.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// AllFiles returns every file in the manifest.
+func (m *DB) AllFiles() ([]Entry, error) {
+	rows, err := m.db.Query(
+		`SELECT f.id, f.device_serial, f.remote_path, f.local_path, f.size, f.mtime, f.sha256, f.pulled_at,
+		        f.captured_at, f.duration_ms, f.width, f.height, f.codec, f.app, COALESCE(al.label, '')
+		 FROM files f
+		 LEFT JOIN app_labels al ON al.package = f.app
+		 ORDER BY f.id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("all files: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var pulledAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.DeviceSerial, &e.RemotePath, &e.LocalPath, &e.Size, &e.MTime, &e.SHA256, &pulledAt,
+			&e.CapturedAt, &e.DurationMS, &e.Width, &e.Height, &e.Codec, &e.App, &e.AppLabel); err != nil {
+			return nil, fmt.Errorf("scan all files: %w", err)
+		}
+		if pulledAt.Valid {
+			e.PulledAt = &pulledAt.Time
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// FileStatus is a file together with every sync recorded for it.
+type FileStatus struct {
+	Entry
+	Syncs []DestSync
+}
+
+// Verified reports whether the file's upload to destination has been
+// verified there.
+func (f FileStatus) Verified(destination string) bool {
+	for _, ds := range f.Syncs {
+		if ds.Destination == destination && ds.VerifiedAt != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// FileStatuses returns every file in the manifest with its syncs.
+func (m *DB) FileStatuses() ([]FileStatus, error) {
+	files, err := m.AllFiles()
+	if err != nil {
+		return nil, err
+	}
+	synced, err := m.querySynced(`1 = 1`)
+	if err != nil {
+		return nil, err
+	}
+	syncs := make(map[int64][]DestSync)
+	for _, e := range synced {
+		syncs[e.ID] = append(syncs[e.ID], e.Sync)
+	}
+	statuses := make([]FileStatus, len(files))
+	for i, f := range files {
+		statuses[i] = FileStatus{Entry: f, Syncs: syncs[f.ID]}
+	}
+	return statuses, nil
+}
+
+// GetFullySyncedFiles returns files synced to and verified at ALL the given
+// destinations for a device (safe to clean). Imported files that aren't on
+// the headset are left out.
 func (m *DB) GetFullySyncedFiles(deviceSerial string, destinations []string) ([]Entry, error) {
 	if len(destinations) == 0 {
 		return nil, nil
 	}
+	args := []interface{}{deviceSerial}
+	for _, d := range destinations {
+		args = append(args, d)
+	}
+	args = append(args, len(destinations))
 	rows, err := m.db.Query(
 		`SELECT f.id, f.device_serial, f.remote_path, f.local_path, f.size, f.mtime
 		 FROM files f
-		 WHERE f.device_serial = ?
-		   AND (SELECT COUNT(DISTINCT ds.destination) FROM dest_syncs ds WHERE ds.file_id = f.id) >= ?`,
-		deviceSerial, len(destinations),
+		 WHERE f.device_serial = ? AND f.remote_path NOT LIKE 'import://%'
+		   AND (SELECT COUNT(DISTINCT ds.destination) FROM dest_syncs ds
+		        WHERE ds.file_id = f.id AND ds.verified_at IS NOT NULL
+		          AND ds.destination IN (`+placeholders(len(destinations))+`)) >= ?`,
+		args...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("get fully synced: %w", err)
@@ -165,13 +542,15 @@ func (m *DB) GetFullySyncedFiles(deviceSerial string, destinations []string) ([]
 	return entries, rows.Err()
 }
 
-// GetAnySyncedFiles returns files synced to at least one destination for a device.
+// GetAnySyncedFiles returns files synced to and verified at at least one
+// destination for a device. Imported files that aren't on the headset are
+// left out.
 func (m *DB) GetAnySyncedFiles(deviceSerial string) ([]Entry, error) {
 	rows, err := m.db.Query(
 		`SELECT f.id, f.device_serial, f.remote_path, f.local_path, f.size, f.mtime
 		 FROM files f
-		 WHERE f.device_serial = ?
-		   AND (SELECT COUNT(*) FROM dest_syncs ds WHERE ds.file_id = f.id) >= 1`,
+		 WHERE f.device_serial = ? AND f.remote_path NOT LIKE 'import://%'
+		   AND EXISTS (SELECT 1 FROM dest_syncs ds WHERE ds.file_id = f.id AND ds.verified_at IS NOT NULL)`,
 		deviceSerial,
 	)
 	if err != nil {
@@ -190,7 +569,8 @@ func (m *DB) GetAnySyncedFiles(deviceSerial string) ([]Entry, error) {
 	return entries, rows.Err()
 }
 
-// GetLocalSyncedFiles returns files with a local_path that have been synced to destinations.
+// GetLocalSyncedFiles returns files with a local_path that have been synced to,
+// and verified at, destinations.
 // If anyDest is true, files synced to at least one destination are returned.
 // If anyDest is false, only files synced to all given destinations are returned.
 func (m *DB) GetLocalSyncedFiles(destinations []string, anyDest bool) ([]Entry, error) {
@@ -205,12 +585,17 @@ func (m *DB) GetLocalSyncedFiles(destinations []string, anyDest bool) ([]Entry,
 		query = `SELECT f.id, f.device_serial, f.remote_path, f.local_path, f.size, f.mtime
 			 FROM files f
 			 WHERE f.local_path != ''
-			   AND (SELECT COUNT(*) FROM dest_syncs ds WHERE ds.file_id = f.id) >= 1`
+			   AND EXISTS (SELECT 1 FROM dest_syncs ds WHERE ds.file_id = f.id AND ds.verified_at IS NOT NULL)`
 	} else {
 		query = `SELECT f.id, f.device_serial, f.remote_path, f.local_path, f.size, f.mtime
 			 FROM files f
 			 WHERE f.local_path != ''
-			   AND (SELECT COUNT(DISTINCT ds.destination) FROM dest_syncs ds WHERE ds.file_id = f.id) >= ?`
+			   AND (SELECT COUNT(DISTINCT ds.destination) FROM dest_syncs ds
+			        WHERE ds.file_id = f.id AND ds.verified_at IS NOT NULL
+			          AND ds.destination IN (` + placeholders(len(destinations)) + `)) >= ?`
+		for _, d := range destinations {
+			args = append(args, d)
+		}
 		args = append(args, len(destinations))
 	}
 
@@ -234,7 +619,9 @@ func (m *DB) GetLocalSyncedFiles(destinations []string, anyDest bool) ([]Entry,
 // QueryByLocalPath returns all file entries with the given local_path.
 func (m *DB) QueryByLocalPath(localPath string) ([]Entry, error) {
 	rows, err := m.db.Query(
-		`SELECT id, device_serial, remote_path, local_path, size, mtime FROM files WHERE local_path = ?`,
+		`SELECT id, device_serial, remote_path, local_path, size, mtime,
+		        captured_at, duration_ms, width, height, codec
+		 FROM files WHERE local_path = ?`,
 		localPath,
 	)
 	if err != nil {
@@ -245,7 +632,8 @@ func (m *DB) QueryByLocalPath(localPath string) ([]Entry, error) {
 	var entries []Entry
 	for rows.Next() {
 		var e Entry
-		if err := rows.Scan(&e.ID, &e.DeviceSerial, &e.RemotePath, &e.LocalPath, &e.Size, &e.MTime); err != nil {
+		if err := rows.Scan(&e.ID, &e.DeviceSerial, &e.RemotePath, &e.LocalPath, &e.Size, &e.MTime,
+			&e.CapturedAt, &e.DurationMS, &e.Width, &e.Height, &e.Codec); err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
 		entries = append(entries, e)
@@ -253,6 +641,84 @@ func (m *DB) QueryByLocalPath(localPath string) ([]Entry, error) {
 	return entries, rows.Err()
 }
 
+// RenameDevice moves every row recorded under the device key from to the
+// key to, returning the number of files moved. Where both keys already track
+// the same remote path, the destination syncs, and the local copy, hash
+// and metadata the row under to lacks, are merged into it and the row under
+// from is dropped.
+func (m *DB) RenameDevice(from, to string) (int64, error) {
+	if from == to {
+		return 0, nil
+	}
+	tx, err := m.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("rename device: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Files tracked under both keys: carry what the row under to is missing
+	// and the syncs over, then drop the duplicate.
+	if _, err := tx.Exec(
+		`UPDATE files AS t SET
+		   local_path = COALESCE(NULLIF(t.local_path, ''), f.local_path),
+		   sha256 = COALESCE(NULLIF(t.sha256, ''), f.sha256),
+		   pulled_at = COALESCE(t.pulled_at, f.pulled_at),
+		   captured_at = COALESCE(NULLIF(t.captured_at, 0), f.captured_at),
+		   duration_ms = COALESCE(NULLIF(t.duration_ms, 0), f.duration_ms),
+		   width = COALESCE(NULLIF(t.width, 0), f.width),
+		   height = COALESCE(NULLIF(t.height, 0), f.height),
+		   codec = COALESCE(NULLIF(t.codec, ''), f.codec),
+		   app = COALESCE(NULLIF(t.app, ''), f.app)
+		 FROM files f
+		 WHERE t.device_serial = ? AND f.device_serial = ? AND f.remote_path = t.remote_path`,
+		to, from,
+	); err != nil {
+		return 0, fmt.Errorf("rename device: merge files: %w", err)
+	}
+	if _, err := tx.Exec(
+		`INSERT OR IGNORE INTO dest_syncs (file_id, destination, synced_at, object_path, size, sha256, object_id, verified_at)
+		 SELECT t.id, ds.destination, ds.synced_at, ds.object_path, ds.size, ds.sha256, ds.object_id, ds.verified_at
+		 FROM dest_syncs ds
+		 JOIN files f ON f.id = ds.file_id
+		 JOIN files t ON t.device_serial = ? AND t.remote_path = f.remote_path
+		 WHERE f.device_serial = ?`,
+		to, from,
+	); err != nil {
+		return 0, fmt.Errorf("rename device: merge syncs: %w", err)
+	}
+	if _, err := tx.Exec(
+		`DELETE FROM dest_syncs WHERE file_id IN (
+		   SELECT f.id FROM files f
+		   JOIN files t ON t.device_serial = ? AND t.remote_path = f.remote_path
+		   WHERE f.device_serial = ?)`,
+		to, from,
+	); err != nil {
+		return 0, fmt.Errorf("rename device: drop duplicate syncs: %w", err)
+	}
+	if _, err := tx.Exec(
+		`DELETE FROM relayout_moves WHERE file_id IN (
+		   SELECT f.id FROM files f
+		   JOIN files t ON t.device_serial = ? AND t.remote_path = f.remote_path
+		   WHERE f.device_serial = ?)`,
+		to, from,
+	); err != nil {
+		return 0, fmt.Errorf("rename device: drop duplicate moves: %w", err)
+	}
+	if _, err := tx.Exec(
+		`DELETE FROM files WHERE device_serial = ?
+		   AND remote_path IN (SELECT remote_path FROM files WHERE device_serial = ?)`,
+		from, to,
+	); err != nil {
+		return 0, fmt.Errorf("rename device: drop duplicate files: %w", err)
+	}
+	res, err := tx.Exec(`UPDATE files SET device_serial = ? WHERE device_serial = ?`, to, from)
+	if err != nil {
+		return 0, fmt.Errorf("rename device: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n, tx.Commit()
+}
+
 // CountSyncedTo counts the number of files synced to a given destination.
 func (m *DB) CountSyncedTo(destination string, count *int) error {
 	return m.db.QueryRow(
@@ -287,7 +753,8 @@ func (m *DB) GetDeviceStats(deviceSerial string, numDestinations int) (DeviceSta
 		err = m.db.QueryRow(
 			`SELECT COUNT(*) FROM files f
 			 WHERE f.device_serial = ?
-			   AND (SELECT COUNT(DISTINCT ds.destination) FROM dest_syncs ds WHERE ds.file_id = f.id) >= ?`,
+			   AND (SELECT COUNT(DISTINCT ds.destination) FROM dest_syncs ds
+			        WHERE ds.file_id = f.id AND ds.verified_at IS NOT NULL) >= ?`,
 			deviceSerial, numDestinations,
 		).Scan(&stats.SyncedFiles)
 		if err != nil {
@@ -296,3 +763,8 @@ func (m *DB) GetDeviceStats(deviceSerial string, numDestinations int) (DeviceSta
 	}
 	return stats, nil
 }
+
+// placeholders returns n comma-separated SQL parameter markers.
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
@@ -0,0 +1,342 @@
+package manifest
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// MergeResult counts what a merge changed.
+type MergeResult struct {
+	FilesAdded   int
+	FilesUpdated int
+	SyncsAdded   int
+	SyncsUpdated int
+	Labels       int // app labels added or updated
+}
+
+// Changed reports whether the merge changed anything.
+func (r MergeResult) Changed() bool {
+	return r.FilesAdded+r.FilesUpdated+r.SyncsAdded+r.SyncsUpdated+r.Labels > 0
+}
+
+type fileKey struct{ device, remotePath string }
+
+type syncKey struct {
+	file        fileKey
+	destination string
+}
+
+// Merge folds the manifest at src, typically another computer's backup,
+// into this one. Files are matched by (device_serial, remote_path) and
+// syncs by file and destination. Conflicts are settled the same way on
+// every computer:
+//
+//   - A file's size, mtime and hash come from the most recent pull; a
+//     missing hash or capture metadata is filled in from the other side
+//     when the rest agrees.
+//   - local_path is this computer's copy, so it is never taken from src.
+//     Files only src has are added without one, and a local copy that no
+//     longer matches the winning record is forgotten.
+//   - A sync verified at the destination beats an unverified one, then the
+//     most recently verified (or synced) record wins.
+//   - An app label set by the user beats one from a headset, then the most
+//     recent one wins.
+//
+// src is not modified. Merging is idempotent.
+func (m *DB) Merge(src string) (MergeResult, error) {
+	var res MergeResult
+
+	// Work on a copy brought up to this build's schema.
+	tmp, err := os.MkdirTemp("", "fetchquest-merge-*")
+	if err != nil {
+		return res, fmt.Errorf("merge: %w", err)
+	}
+	defer os.RemoveAll(tmp)
+	if err := copyFile(src, filepath.Join(tmp, "manifest.db")); err != nil {
+		return res, fmt.Errorf("merge: %w", err)
+	}
+	if err := checkBackup(filepath.Join(tmp, "manifest.db")); err != nil {
+		return res, err
+	}
+	other, err := Open(tmp)
+	if err != nil {
+		return res, fmt.Errorf("merge: %w", err)
+	}
+	defer other.Close()
+
+	theirFiles, err := other.AllFiles()
+	if err != nil {
+		return res, err
+	}
+	theirSyncs, err := other.querySynced(`1 = 1`)
+	if err != nil {
+		return res, err
+	}
+	theirApps, err := other.Apps()
+	if err != nil {
+		return res, err
+	}
+	ourApps, err := m.Apps()
+	if err != nil {
+		return res, err
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return res, fmt.Errorf("merge: %w", err)
+	}
+	defer tx.Rollback()
+
+	ours, err := filesByKey(tx)
+	if err != nil {
+		return res, err
+	}
+	ids := make(map[fileKey]int64, len(ours))
+	for k, e := range ours {
+		ids[k] = e.ID
+	}
+
+	for _, t := range theirFiles {
+		k := fileKey{t.DeviceSerial, t.RemotePath}
+		o, ok := ours[k]
+		if !ok {
+			r, err := tx.Exec(
+				`INSERT INTO files (device_serial, remote_path, local_path, size, mtime, sha256, pulled_at,
+				                    captured_at, duration_ms, width, height, codec, app)
+				 VALUES (?, ?, '', ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				t.DeviceSerial, t.RemotePath, t.Size, t.MTime, t.SHA256, t.PulledAt,
+				t.CapturedAt, t.DurationMS, t.Width, t.Height, t.Codec, t.App,
+			)
+			if err != nil {
+				return res, fmt.Errorf("merge file %s: %w", t.RemotePath, err)
+			}
+			if ids[k], err = r.LastInsertId(); err != nil {
+				return res, fmt.Errorf("merge file %s: %w", t.RemotePath, err)
+			}
+			res.FilesAdded++
+			continue
+		}
+
+		merged := o
+		if newerPull(t, o) {
+			merged.Size, merged.MTime, merged.SHA256, merged.PulledAt = t.Size, t.MTime, t.SHA256, t.PulledAt
+			if merged.SHA256 == "" && sameContent(o, t) {
+				merged.SHA256 = o.SHA256
+			}
+		} else if merged.SHA256 == "" && sameContent(o, t) {
+			merged.SHA256 = t.SHA256
+		}
+		if !sameContent(o, merged) || (o.SHA256 != "" && o.SHA256 != merged.SHA256) {
+			merged.LocalPath = ""
+			merged.Media = t.Media
+		} else if merged.Media.IsZero() {
+			merged.Media = t.Media
+		}
+		if merged.Size == o.Size && merged.MTime == o.MTime && merged.SHA256 == o.SHA256 &&
+			merged.LocalPath == o.LocalPath && timeEqual(merged.PulledAt, o.PulledAt) && merged.Media == o.Media {
+			continue
+		}
+		if _, err := tx.Exec(
+			`UPDATE files SET local_path = ?, size = ?, mtime = ?, sha256 = ?, pulled_at = ?,
+			   captured_at = ?, duration_ms = ?, width = ?, height = ?, codec = ? WHERE id = ?`,
+			merged.LocalPath, merged.Size, merged.MTime, merged.SHA256, merged.PulledAt,
+			merged.CapturedAt, merged.DurationMS, merged.Width, merged.Height, merged.Codec, o.ID,
+		); err != nil {
+			return res, fmt.Errorf("merge file %s: %w", t.RemotePath, err)
+		}
+		res.FilesUpdated++
+	}
+
+	ourSyncs, err := syncsByKey(tx)
+	if err != nil {
+		return res, err
+	}
+	for _, t := range theirSyncs {
+		k := syncKey{fileKey{t.DeviceSerial, t.RemotePath}, t.Sync.Destination}
+		fileID := ids[k.file]
+		o, ok := ourSyncs[k]
+		if ok && !betterSync(t.Sync, o) {
+			continue
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO dest_syncs (file_id, destination, synced_at, object_path, size, sha256, object_id, verified_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT(file_id, destination) DO UPDATE SET
+			   synced_at = excluded.synced_at,
+			   object_path = excluded.object_path,
+			   size = excluded.size,
+			   sha256 = excluded.sha256,
+			   object_id = excluded.object_id,
+			   verified_at = excluded.verified_at`,
+			fileID, t.Sync.Destination, t.Sync.SyncedAt, t.Sync.ObjectPath, t.Sync.Size,
+			t.Sync.SHA256, t.Sync.ObjectID, t.Sync.VerifiedAt,
+		); err != nil {
+			return res, fmt.Errorf("merge sync %s: %w", t.RemotePath, err)
+		}
+		if ok {
+			res.SyncsUpdated++
+		} else {
+			res.SyncsAdded++
+		}
+	}
+
+	ourLabels := make(map[string]App)
+	for _, a := range ourApps {
+		ourLabels[a.Package] = a
+	}
+	for _, t := range theirApps {
+		if t.Label == "" || !betterLabel(t, ourLabels[t.Package]) {
+			continue
+		}
+		if _, err := tx.Exec(
+			`INSERT INTO app_labels (package, label, source, updated_at) VALUES (?, ?, ?, ?)
+			 ON CONFLICT(package) DO UPDATE SET
+			   label = excluded.label,
+			   source = excluded.source,
+			   updated_at = excluded.updated_at`,
+			t.Package, t.Label, t.Source, t.UpdatedAt,
+		); err != nil {
+			return res, fmt.Errorf("merge app label %s: %w", t.Package, err)
+		}
+		res.Labels++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return res, fmt.Errorf("merge: %w", err)
+	}
+	return res, nil
+}
+
+// betterLabel reports whether app label a should replace b.
+func betterLabel(a, b App) bool {
+	if b.Label == "" {
+		return true
+	}
+	if a.Label == b.Label && a.Source == b.Source {
+		return false
+	}
+	if (a.Source == LabelFromUser) != (b.Source == LabelFromUser) {
+		return a.Source == LabelFromUser
+	}
+	return a.UpdatedAt != nil && (b.UpdatedAt == nil || a.UpdatedAt.After(*b.UpdatedAt))
+}
+
+// newerPull reports whether a describes a later pull than b. Records
+// pulled at the same moment are ordered by content so both sides of a
+// merge pick the same one.
+func newerPull(a, b Entry) bool {
+	at, bt := pullTime(a), pullTime(b)
+	if !at.Equal(bt) {
+		return at.After(bt)
+	}
+	if a.MTime != b.MTime {
+		return a.MTime > b.MTime
+	}
+	if a.Size != b.Size {
+		return a.Size > b.Size
+	}
+	return a.SHA256 > b.SHA256
+}
+
+func pullTime(e Entry) time.Time {
+	if e.PulledAt == nil {
+		return time.Time{}
+	}
+	return *e.PulledAt
+}
+
+// sameContent reports whether two records describe the same file version.
+func sameContent(a, b Entry) bool {
+	return a.Size == b.Size && a.MTime == b.MTime &&
+		(a.SHA256 == "" || b.SHA256 == "" || a.SHA256 == b.SHA256)
+}
+
+func timeEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// betterSync reports whether a should replace b.
+func betterSync(a, b DestSync) bool {
+	if (a.VerifiedAt != nil) != (b.VerifiedAt != nil) {
+		return a.VerifiedAt != nil
+	}
+	if a.VerifiedAt != nil && !a.VerifiedAt.Equal(*b.VerifiedAt) {
+		return a.VerifiedAt.After(*b.VerifiedAt)
+	}
+	return a.SyncedAt.After(b.SyncedAt)
+}
+
+func filesByKey(tx *sql.Tx) (map[fileKey]Entry, error) {
+	rows, err := tx.Query(
+		`SELECT id, device_serial, remote_path, local_path, size, mtime, sha256, pulled_at,
+		        captured_at, duration_ms, width, height, codec
+		 FROM files`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("merge: read files: %w", err)
+	}
+	defer rows.Close()
+	files := make(map[fileKey]Entry)
+	for rows.Next() {
+		var e Entry
+		var pulledAt sql.NullTime
+		if err := rows.Scan(&e.ID, &e.DeviceSerial, &e.RemotePath, &e.LocalPath, &e.Size, &e.MTime, &e.SHA256, &pulledAt,
+			&e.CapturedAt, &e.DurationMS, &e.Width, &e.Height, &e.Codec); err != nil {
+			return nil, fmt.Errorf("merge: read files: %w", err)
+		}
+		if pulledAt.Valid {
+			e.PulledAt = &pulledAt.Time
+		}
+		files[fileKey{e.DeviceSerial, e.RemotePath}] = e
+	}
+	return files, rows.Err()
+}
+
+func syncsByKey(tx *sql.Tx) (map[syncKey]DestSync, error) {
+	rows, err := tx.Query(
+		`SELECT f.device_serial, f.remote_path, ds.destination, ds.synced_at, ds.verified_at
+		 FROM dest_syncs ds JOIN files f ON f.id = ds.file_id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("merge: read syncs: %w", err)
+	}
+	defer rows.Close()
+	syncs := make(map[syncKey]DestSync)
+	for rows.Next() {
+		var k syncKey
+		var ds DestSync
+		var verifiedAt sql.NullTime
+		if err := rows.Scan(&k.file.device, &k.file.remotePath, &k.destination, &ds.SyncedAt, &verifiedAt); err != nil {
+			return nil, fmt.Errorf("merge: read syncs: %w", err)
+		}
+		if verifiedAt.Valid {
+			ds.VerifiedAt = &verifiedAt.Time
+		}
+		syncs[k] = ds
+	}
+	return syncs, rows.Err()
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,388 @@
+package manifest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/FluidXR/fetchquest/internal/media"
+)
+
+// migrations bring the schema from version i to i+1, where the version is
+// kept in PRAGMA user_version. Each runs in its own transaction. Released
+// migrations must never change: add a new one instead.
+//
+// Databases from before versioning report version 0 but may already have
+// some of these tables and columns, so the early migrations only create
+// what is missing.
+var migrations = []func(tx *sql.Tx) error{
+	// 1: files and dest_syncs.
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS files (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			device_serial TEXT NOT NULL,
+			remote_path TEXT NOT NULL,
+			local_path TEXT NOT NULL DEFAULT '',
+			size INTEGER NOT NULL DEFAULT 0,
+			mtime INTEGER NOT NULL DEFAULT 0,
+			sha256 TEXT NOT NULL DEFAULT '',
+			pulled_at DATETIME,
+			UNIQUE(device_serial, remote_path)
+		);
+
+		CREATE TABLE IF NOT EXISTS dest_syncs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			file_id INTEGER NOT NULL,
+			destination TEXT NOT NULL,
+			synced_at DATETIME NOT NULL,
+			FOREIGN KEY (file_id) REFERENCES files(id),
+			UNIQUE(file_id, destination)
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_files_device ON files(device_serial);
+		CREATE INDEX IF NOT EXISTS idx_dest_syncs_file ON dest_syncs(file_id);`)
+		return err
+	},
+	// 2: destination and daemon status for the push daemon.
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS dest_status (
+			destination TEXT PRIMARY KEY,
+			reachable INTEGER NOT NULL DEFAULT 0,
+			failures INTEGER NOT NULL DEFAULT 0,
+			last_error TEXT NOT NULL DEFAULT '',
+			checked_at DATETIME,
+			pushed_at DATETIME,
+			next_retry DATETIME
+		);
+
+		CREATE TABLE IF NOT EXISTS daemon_status (
+			id INTEGER PRIMARY KEY CHECK (id = 1),
+			pid INTEGER NOT NULL,
+			started_at DATETIME NOT NULL,
+			heartbeat_at DATETIME NOT NULL
+		);`)
+		return err
+	},
+	// 3: hash confirmed at each destination.
+	func(tx *sql.Tx) error {
+		return addColumn(tx, "dest_syncs", "sha256", "TEXT NOT NULL DEFAULT ''")
+	},
+	// 4: when each upload was verified.
+	func(tx *sql.Tx) error {
+		return addColumn(tx, "dest_syncs", "verified_at", "DATETIME")
+	},
+	// 5: what was actually written to each destination.
+	func(tx *sql.Tx) error {
+		for _, col := range []struct{ name, decl string }{
+			{"object_path", "TEXT NOT NULL DEFAULT ''"},
+			{"size", "INTEGER NOT NULL DEFAULT 0"},
+			{"object_id", "TEXT NOT NULL DEFAULT ''"},
+		} {
+			if err := addColumn(tx, "dest_syncs", col.name, col.decl); err != nil {
+				return err
+			}
+		}
+		return backfillDestSyncs(tx)
+	},
+	// 6: moves still to be made by a relayout.
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS relayout_moves (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			file_id INTEGER NOT NULL,
+			destination TEXT NOT NULL DEFAULT '',
+			from_path TEXT NOT NULL,
+			to_path TEXT NOT NULL,
+			FOREIGN KEY (file_id) REFERENCES files(id)
+		);`)
+		return err
+	},
+	// 7: capture metadata read from the files themselves.
+	func(tx *sql.Tx) error {
+		for _, col := range []struct{ name, decl string }{
+			{"captured_at", "INTEGER NOT NULL DEFAULT 0"},
+			{"duration_ms", "INTEGER NOT NULL DEFAULT 0"},
+			{"width", "INTEGER NOT NULL DEFAULT 0"},
+			{"height", "INTEGER NOT NULL DEFAULT 0"},
+			{"codec", "TEXT NOT NULL DEFAULT ''"},
+		} {
+			if err := addColumn(tx, "files", col.name, col.decl); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	// 8: the app each capture was taken in, and app labels from headsets.
+	func(tx *sql.Tx) error {
+		if err := addColumn(tx, "files", "app", "TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`
+		CREATE TABLE IF NOT EXISTS app_labels (
+			package TEXT PRIMARY KEY,
+			label TEXT NOT NULL,
+			source TEXT NOT NULL,
+			updated_at DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_files_app ON files(app);`); err != nil {
+			return err
+		}
+		return backfillApps(tx)
+	},
+}
+
+// SchemaVersion is the manifest schema version this build writes.
+var SchemaVersion = len(migrations)
+
+// ErrNewerSchema is returned when the manifest was written by a newer
+// version of fetchquest.
+var ErrNewerSchema = errors.New("manifest was created by a newer version of fetchquest")
+
+// schemaVersion reads PRAGMA user_version.
+func schemaVersion(db *sql.DB) (int, error) {
+	var v int
+	if err := db.QueryRow(`PRAGMA user_version`).Scan(&v); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return v, nil
+}
+
+// migrate brings the schema up to SchemaVersion. Before changing an
+// existing database it writes a copy next to it (manifest.db.v<N>.bak).
+func (m *DB) migrate() error {
+	version, err := schemaVersion(m.db)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: %s is schema version %d, this version understands up to %d — upgrade fetchquest",
+			ErrNewerSchema, m.path, version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return nil
+	}
+
+	if existing, err := m.hasTables(); err != nil {
+		return err
+	} else if existing {
+		backup := fmt.Sprintf("%s.v%d.bak", m.path, version)
+		if err := m.Snapshot(backup); err != nil {
+			return fmt.Errorf("back up manifest before migrating: %w", err)
+		}
+	}
+
+	for v := version; v < SchemaVersion; v++ {
+		tx, err := m.db.Begin()
+		if err != nil {
+			return fmt.Errorf("migrate to v%d: %w", v+1, err)
+		}
+		if err := migrations[v](tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrate to v%d: %w", v+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, v+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migrate to v%d: %w", v+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migrate to v%d: %w", v+1, err)
+		}
+	}
+	return nil
+}
+
+// hasTables reports whether the database has any tables yet.
+func (m *DB) hasTables() (bool, error) {
+	var n int
+	if err := m.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'`).Scan(&n); err != nil {
+		return false, fmt.Errorf("inspect schema: %w", err)
+	}
+	return n > 0, nil
+}
+
+// Snapshot writes a consistent copy of the database, including anything
+// still in the WAL, to path. Unlike copying manifest.db, it is safe while
+// the database is in use.
+func (m *DB) Snapshot(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if _, err := m.db.Exec(`VACUUM INTO ?`, path); err != nil {
+		return fmt.Errorf("vacuum into %s: %w", path, err)
+	}
+	return nil
+}
+
+// addColumn adds a column to an existing table unless it is already there.
+func addColumn(tx *sql.Tx, table, column, decl string) error {
+	rows, err := tx.Query(`SELECT name FROM pragma_table_info(?)`, table)
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("inspect %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	rows.Close()
+	if _, err := tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl)); err != nil {
+		return fmt.Errorf("add %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+// backfillDestSyncs fills in object metadata for syncs recorded before it
+// was stored. Uploads mirrored the sync dir, so a file pulled to
+// <sync_dir>/Videos/x.mp4 was uploaded as Videos/x.mp4. Files streamed
+// without a local copy are left for the next verification to fill in.
+func backfillDestSyncs(tx *sql.Tx) error {
+	if _, err := tx.Exec(
+		`UPDATE dest_syncs SET size = (SELECT size FROM files WHERE files.id = dest_syncs.file_id)
+		 WHERE size = 0`,
+	); err != nil {
+		return fmt.Errorf("backfill sizes: %w", err)
+	}
+
+	rows, err := tx.Query(
+		`SELECT ds.id, f.local_path FROM dest_syncs ds
+		 JOIN files f ON f.id = ds.file_id
+		 WHERE ds.object_path = '' AND f.local_path != ''`,
+	)
+	if err != nil {
+		return fmt.Errorf("backfill object paths: %w", err)
+	}
+	paths := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var local string
+		if err := rows.Scan(&id, &local); err != nil {
+			rows.Close()
+			return fmt.Errorf("backfill object paths: %w", err)
+		}
+		paths[id] = filepath.Base(filepath.Dir(local)) + "/" + filepath.Base(local)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("backfill object paths: %w", err)
+	}
+	for id, p := range paths {
+		if _, err := tx.Exec(`UPDATE dest_syncs SET object_path = ? WHERE id = ?`, p, id); err != nil {
+			return fmt.Errorf("backfill object paths: %w", err)
+		}
+	}
+	return nil
+}
+
+// backfillApps fills in the app, and the capture time where none was read
+// from the file, from the names of files recorded before they were stored.
+func backfillApps(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, remote_path FROM files WHERE app = ''`)
+	if err != nil {
+		return fmt.Errorf("backfill apps: %w", err)
+	}
+	type parsed struct {
+		app string
+		at  int64
+	}
+	found := make(map[int64]parsed)
+	for rows.Next() {
+		var id int64
+		var remotePath string
+		if err := rows.Scan(&id, &remotePath); err != nil {
+			rows.Close()
+			return fmt.Errorf("backfill apps: %w", err)
+		}
+		if app, at, ok := media.ParseName(path.Base(remotePath)); ok {
+			p := parsed{app: app}
+			if !at.IsZero() {
+				p.at = at.Unix()
+			}
+			found[id] = p
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("backfill apps: %w", err)
+	}
+	for id, p := range found {
+		if _, err := tx.Exec(
+			`UPDATE files SET app = ?, captured_at = CASE WHEN captured_at = 0 THEN ? ELSE captured_at END WHERE id = ?`,
+			p.app, p.at, id,
+		); err != nil {
+			return fmt.Errorf("backfill apps: %w", err)
+		}
+	}
+	return nil
+}
+
+// Restore replaces the manifest in configDir with the database at src,
+// typically a downloaded backup in configDir. The backup is checked first: it must be
+// a readable manifest no newer than this build understands. Any WAL left
+// by the old database is removed so it can't be replayed onto the new
+// one, and the restored manifest is upgraded to the current schema.
+func Restore(configDir, src string) error {
+	err := checkBackup(src)
+	// Opening the backup may leave WAL files beside it.
+	os.Remove(src + "-wal")
+	os.Remove(src + "-shm")
+	if err != nil {
+		return err
+	}
+	dbPath := filepath.Join(configDir, "manifest.db")
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("restore: %w", err)
+		}
+	}
+	if err := os.Rename(src, dbPath); err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	m, err := Open(configDir)
+	if err != nil {
+		return fmt.Errorf("restore: %w", err)
+	}
+	return m.Close()
+}
+
+// checkBackup validates a manifest file before it replaces the live one.
+func checkBackup(path string) error {
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return fmt.Errorf("open backup: %w", err)
+	}
+	defer db.Close()
+
+	var result string
+	if err := db.QueryRow(`PRAGMA quick_check`).Scan(&result); err != nil {
+		return fmt.Errorf("backup is not a valid manifest: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("backup is damaged: %s", result)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'files'`).Scan(&n); err != nil || n == 0 {
+		return fmt.Errorf("backup is not a fetchquest manifest")
+	}
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion {
+		return fmt.Errorf("%w: backup is schema version %d, this version understands up to %d — upgrade fetchquest",
+			ErrNewerSchema, version, SchemaVersion)
+	}
+	return nil
+}
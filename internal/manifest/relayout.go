@@ -0,0 +1,92 @@
+package manifest
+
+import (
+	"fmt"
+)
+
+// PendingMove is one copy of a file that a relayout still has to move.
+// Destination is empty for the local copy; From and To are then local
+// paths, and To is empty if the local copy is only forgotten. Otherwise
+// they are object paths.
+type PendingMove struct {
+	ID          int64
+	FileID      int64
+	Destination string
+	From, To    string
+}
+
+// Local reports whether the move is of the local copy.
+func (p PendingMove) Local() bool {
+	return p.Destination == ""
+}
+
+// SaveRelayout replaces any pending relayout with moves.
+func (m *DB) SaveRelayout(moves []PendingMove) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("save relayout: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM relayout_moves`); err != nil {
+		return fmt.Errorf("save relayout: %w", err)
+	}
+	for _, mv := range moves {
+		_, err := tx.Exec(
+			`INSERT INTO relayout_moves (file_id, destination, from_path, to_path) VALUES (?, ?, ?, ?)`,
+			mv.FileID, mv.Destination, mv.From, mv.To,
+		)
+		if err != nil {
+			return fmt.Errorf("save relayout: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// PendingRelayout returns the moves left from an unfinished relayout,
+// grouped by file.
+func (m *DB) PendingRelayout() ([]PendingMove, error) {
+	rows, err := m.db.Query(
+		`SELECT id, file_id, destination, from_path, to_path FROM relayout_moves ORDER BY file_id, id`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("pending relayout: %w", err)
+	}
+	defer rows.Close()
+	var moves []PendingMove
+	for rows.Next() {
+		var mv PendingMove
+		if err := rows.Scan(&mv.ID, &mv.FileID, &mv.Destination, &mv.From, &mv.To); err != nil {
+			return nil, fmt.Errorf("pending relayout: %w", err)
+		}
+		moves = append(moves, mv)
+	}
+	return moves, rows.Err()
+}
+
+// CompleteMoves records in one transaction that moves were made: the
+// file's local path or object path is updated, or cleared for an empty To,
+// and the moves are no longer pending.
+func (m *DB) CompleteMoves(moves []PendingMove) error {
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("complete moves: %w", err)
+	}
+	defer tx.Rollback()
+	for _, mv := range moves {
+		if mv.Local() {
+			_, err = tx.Exec(`UPDATE files SET local_path = ? WHERE id = ?`, mv.To, mv.FileID)
+		} else {
+			_, err = tx.Exec(
+				`UPDATE dest_syncs SET object_path = ? WHERE file_id = ? AND destination = ?`,
+				mv.To, mv.FileID, mv.Destination,
+			)
+		}
+		if err != nil {
+			return fmt.Errorf("complete moves: %w", err)
+		}
+		if _, err := tx.Exec(`DELETE FROM relayout_moves WHERE id = ?`, mv.ID); err != nil {
+			return fmt.Errorf("complete moves: %w", err)
+		}
+	}
+	return tx.Commit()
+}
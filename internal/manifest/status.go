@@ -0,0 +1,155 @@
+package manifest
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// DestStatus is the last known state of a destination, as recorded by
+// sync runs and the daemon.
+type DestStatus struct {
+	Destination string
+	Reachable   bool
+	Failures    int // consecutive failed probes
+	LastError   string
+	CheckedAt   *time.Time
+	PushedAt    *time.Time
+	NextRetry   *time.Time // when the daemon will probe again, if it is waiting
+	Queued      int        // local files not yet pushed to this destination
+}
+
+// RecordDestProbe stores the outcome of a reachability check. A failure
+// increments the failure count; a success resets it. nextRetry may be zero.
+func (m *DB) RecordDestProbe(destination string, reachable bool, errMsg string, nextRetry time.Time) error {
+	var next any
+	if !nextRetry.IsZero() {
+		next = nextRetry
+	}
+	_, err := m.db.Exec(
+		`INSERT INTO dest_status (destination, reachable, failures, last_error, checked_at, next_retry)
+		 VALUES (?, ?, CASE WHEN ? THEN 0 ELSE 1 END, ?, ?, ?)
+		 ON CONFLICT(destination) DO UPDATE SET
+		   reachable = excluded.reachable,
+		   failures = CASE WHEN excluded.reachable THEN 0 ELSE dest_status.failures + 1 END,
+		   last_error = excluded.last_error,
+		   checked_at = excluded.checked_at,
+		   next_retry = excluded.next_retry`,
+		destination, reachable, reachable, errMsg, time.Now(), next,
+	)
+	if err != nil {
+		return fmt.Errorf("record dest probe: %w", err)
+	}
+	return nil
+}
+
+// RecordDestPushed notes that files were just pushed to a destination.
+func (m *DB) RecordDestPushed(destination string) error {
+	now := time.Now()
+	_, err := m.db.Exec(
+		`INSERT INTO dest_status (destination, reachable, checked_at, pushed_at)
+		 VALUES (?, 1, ?, ?)
+		 ON CONFLICT(destination) DO UPDATE SET pushed_at = excluded.pushed_at`,
+		destination, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("record dest push: %w", err)
+	}
+	return nil
+}
+
+// CountQueued returns the number of local files waiting to be pushed to a
+// destination. Files pulled in skip-local mode have no local copy and are
+// not counted.
+func (m *DB) CountQueued(destination string) (int, error) {
+	var n int
+	err := m.db.QueryRow(
+		`SELECT COUNT(*) FROM files f
+		 WHERE f.pulled_at IS NOT NULL AND f.local_path != ''
+		   AND f.id NOT IN (SELECT file_id FROM dest_syncs WHERE destination = ?)`,
+		destination,
+	).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("count queued: %w", err)
+	}
+	return n, nil
+}
+
+// GetDestStatuses returns the recorded status and queue length of each
+// destination, in the order given. Destinations never checked are
+// returned with only Destination and Queued set.
+func (m *DB) GetDestStatuses(destinations []string) ([]DestStatus, error) {
+	var out []DestStatus
+	for _, dest := range destinations {
+		s := DestStatus{Destination: dest}
+		var checked, pushed, next sql.NullTime
+		err := m.db.QueryRow(
+			`SELECT reachable, failures, last_error, checked_at, pushed_at, next_retry
+			 FROM dest_status WHERE destination = ?`, dest,
+		).Scan(&s.Reachable, &s.Failures, &s.LastError, &checked, &pushed, &next)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("get dest status: %w", err)
+		}
+		s.CheckedAt = nullTime(checked)
+		s.PushedAt = nullTime(pushed)
+		s.NextRetry = nullTime(next)
+		if s.Queued, err = m.CountQueued(dest); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, nil
+}
+
+func nullTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// DaemonStatus describes the running daemon, if any.
+type DaemonStatus struct {
+	PID         int
+	StartedAt   time.Time
+	HeartbeatAt time.Time
+}
+
+// RecordDaemonHeartbeat marks the daemon with the given pid as alive.
+func (m *DB) RecordDaemonHeartbeat(pid int, startedAt time.Time) error {
+	_, err := m.db.Exec(
+		`INSERT INTO daemon_status (id, pid, started_at, heartbeat_at) VALUES (1, ?, ?, ?)
+		 ON CONFLICT(id) DO UPDATE SET
+		   pid = excluded.pid, started_at = excluded.started_at, heartbeat_at = excluded.heartbeat_at`,
+		pid, startedAt, time.Now(),
+	)
+	if err != nil {
+		return fmt.Errorf("record daemon heartbeat: %w", err)
+	}
+	return nil
+}
+
+// ClearDaemon removes the daemon record if it belongs to pid.
+func (m *DB) ClearDaemon(pid int) error {
+	_, err := m.db.Exec(`DELETE FROM daemon_status WHERE pid = ?`, pid)
+	if err != nil {
+		return fmt.Errorf("clear daemon status: %w", err)
+	}
+	return nil
+}
+
+// GetDaemonStatus returns the last daemon heartbeat, or nil if no daemon
+// has registered.
+func (m *DB) GetDaemonStatus() (*DaemonStatus, error) {
+	var s DaemonStatus
+	err := m.db.QueryRow(
+		`SELECT pid, started_at, heartbeat_at FROM daemon_status WHERE id = 1`,
+	).Scan(&s.PID, &s.StartedAt, &s.HeartbeatAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get daemon status: %w", err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,86 @@
+package media
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// probePNG reads the dimensions from the IHDR chunk, which always comes
+// first.
+func probePNG(r io.Reader) (Info, error) {
+	var b [24]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return Info{}, err
+	}
+	if string(b[12:16]) != "IHDR" {
+		return Info{}, errors.New("png: missing IHDR")
+	}
+	return Info{
+		Width:  int(binary.BigEndian.Uint32(b[16:20])),
+		Height: int(binary.BigEndian.Uint32(b[20:24])),
+	}, nil
+}
+
+// probeJPEG reads the dimensions from the first start-of-frame segment.
+func probeJPEG(r io.Reader) (Info, error) {
+	br := bufio.NewReader(r)
+	var soi [2]byte
+	if _, err := io.ReadFull(br, soi[:]); err != nil {
+		return Info{}, err
+	}
+	for {
+		// Markers are 0xFF followed by a code; extra 0xFF bytes are padding.
+		c, err := br.ReadByte()
+		if err != nil {
+			return Info{}, err
+		}
+		if c != 0xFF {
+			continue
+		}
+		marker, err := br.ReadByte()
+		for err == nil && marker == 0xFF {
+			marker, err = br.ReadByte()
+		}
+		if err != nil {
+			return Info{}, err
+		}
+		switch {
+		case marker == 0x01 || marker >= 0xD0 && marker <= 0xD8:
+			continue // no payload
+		case marker == 0xD9 || marker == 0xDA:
+			return Info{}, errors.New("jpeg: no frame header before image data")
+		}
+
+		var l [2]byte
+		if _, err := io.ReadFull(br, l[:]); err != nil {
+			return Info{}, err
+		}
+		length := int(binary.BigEndian.Uint16(l[:])) - 2
+		if length < 0 {
+			return Info{}, errors.New("jpeg: bad segment length")
+		}
+		if isSOF(marker) && length >= 5 {
+			var sof [5]byte
+			if _, err := io.ReadFull(br, sof[:]); err != nil {
+				return Info{}, err
+			}
+			return Info{
+				Height: int(binary.BigEndian.Uint16(sof[1:3])),
+				Width:  int(binary.BigEndian.Uint16(sof[3:5])),
+			}, nil
+		}
+		if _, err := br.Discard(length); err != nil {
+			return Info{}, err
+		}
+	}
+}
+
+// isSOF reports whether marker starts a frame: SOF0-SOF15 except DHT,
+// JPG and DAC, which share the range.
+func isSOF(marker byte) bool {
+	return marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC
+}
@@ -0,0 +1,117 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// pngHeader builds the signature and IHDR chunk of a width x height PNG.
+func pngHeader(width, height int) []byte {
+	ihdr := binary.BigEndian.AppendUint32(nil, uint32(width))
+	ihdr = binary.BigEndian.AppendUint32(ihdr, uint32(height))
+	ihdr = append(ihdr, 8, 6, 0, 0, 0)
+	b := append([]byte(nil), pngSignature...)
+	b = binary.BigEndian.AppendUint32(b, uint32(len(ihdr)))
+	b = append(b, "IHDR"...)
+	b = append(b, ihdr...)
+	return append(b, 0, 0, 0, 0) // CRC, unchecked
+}
+
+// segment builds a JPEG marker segment.
+func segment(marker byte, payload []byte) []byte {
+	b := []byte{0xFF, marker}
+	b = binary.BigEndian.AppendUint16(b, uint16(2+len(payload)))
+	return append(b, payload...)
+}
+
+// sof builds a start-of-frame payload for a width x height image.
+func sof(width, height int) []byte {
+	p := []byte{8}
+	p = binary.BigEndian.AppendUint16(p, uint16(height))
+	p = binary.BigEndian.AppendUint16(p, uint16(width))
+	return append(p, 3, 1, 0x22, 0, 2, 0x11, 1, 3, 0x11, 1)
+}
+
+var (
+	soi  = []byte{0xFF, 0xD8}
+	app0 = segment(0xE0, []byte("JFIF\x00\x01\x01\x00\x00\x01\x00\x01\x00\x00"))
+	// An Exif segment whose embedded thumbnail has its own, smaller
+	// frame header, which must be skipped over.
+	app1 = segment(0xE1, bytes.Join([][]byte{[]byte("Exif\x00\x00MM\x00\x2a"), soi, segment(0xC0, sof(160, 120)), {0xFF, 0xD9}}, nil))
+	dqt  = segment(0xDB, make([]byte, 65))
+	dht  = segment(0xC4, make([]byte, 29))
+	sos  = segment(0xDA, []byte{1, 1, 0, 0, 0x3F, 0})
+)
+
+func TestProbeImage(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Info
+		wantErr bool
+	}{
+		{
+			name: "png",
+			data: append(pngHeader(3664, 1920), make([]byte, 32)...),
+			want: Info{Width: 3664, Height: 1920},
+		},
+		{
+			name:    "truncated png",
+			data:    pngHeader(3664, 1920)[:20],
+			wantErr: true,
+		},
+		{
+			name:    "png without IHDR",
+			data:    bytes.Replace(pngHeader(3664, 1920), []byte("IHDR"), []byte("tEXt"), 1),
+			wantErr: true,
+		},
+		{
+			name: "jpeg",
+			data: bytes.Join([][]byte{soi, app0, dqt, segment(0xC0, sof(1920, 1080)), dht, sos}, nil),
+			want: Info{Width: 1920, Height: 1080},
+		},
+		{
+			name: "jpeg with APP1 before SOF0",
+			data: bytes.Join([][]byte{soi, app1, dqt, dht, segment(0xC0, sof(4032, 3024)), sos}, nil),
+			want: Info{Width: 4032, Height: 3024},
+		},
+		{
+			name: "progressive jpeg",
+			data: bytes.Join([][]byte{soi, app0, segment(0xC2, sof(2048, 1536)), sos}, nil),
+			want: Info{Width: 2048, Height: 1536},
+		},
+		{
+			name: "fill bytes before a marker",
+			data: bytes.Join([][]byte{soi, app0, {0xFF, 0xFF, 0xFF}, segment(0xC0, sof(800, 600)), sos}, nil),
+			want: Info{Width: 800, Height: 600},
+		},
+		{
+			name:    "jpeg without a frame header",
+			data:    bytes.Join([][]byte{soi, app0, dqt, dht, sos}, nil),
+			wantErr: true,
+		},
+		{
+			name:    "truncated jpeg",
+			data:    bytes.Join([][]byte{soi, app1}, nil)[:24],
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probeBytes(t, tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Probe = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("Probe = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package media reads capture metadata from the files a Quest produces:
+// MP4 recordings and PNG/JPEG screenshots and photos. It only reads the
+// container headers, so it's fast and needs no external tools.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Info is what could be read from a file. Zero values mean unknown.
+type Info struct {
+	CapturedAt time.Time     // when recording started (MP4 only)
+	Duration   time.Duration // MP4 only
+	Width      int
+	Height     int
+	Codec      string // video codec, e.g. "h264" or "hevc"
+}
+
+// ErrUnknownFormat is returned for files that are not MP4, PNG or JPEG.
+var ErrUnknownFormat = errors.New("unknown media format")
+
+// Probe reads the metadata of the file at path.
+func Probe(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+
+	var head [12]byte
+	n, err := io.ReadFull(f, head[:])
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return Info{}, fmt.Errorf("probe %s: %w", path, err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return Info{}, fmt.Errorf("probe %s: %w", path, err)
+	}
+
+	var info Info
+	switch h := head[:n]; {
+	case bytes.HasPrefix(h, pngSignature):
+		info, err = probePNG(f)
+	case bytes.HasPrefix(h, []byte{0xFF, 0xD8, 0xFF}):
+		info, err = probeJPEG(f)
+	case n >= 8 && isTopLevelBox(string(h[4:8])):
+		info, err = probeMP4(f)
+	default:
+		return Info{}, ErrUnknownFormat
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("probe %s: %w", path, err)
+	}
+	return info, nil
+}
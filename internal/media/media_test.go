@@ -0,0 +1,26 @@
+package media
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// probeBytes writes data to a temporary file and probes it.
+func probeBytes(t *testing.T, data []byte) (Info, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "capture")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Probe(path)
+}
+
+func TestProbeUnknownFormat(t *testing.T) {
+	for _, data := range [][]byte{[]byte("GIF89a\x01\x00\x01\x00"), []byte("not a capture at all")} {
+		if _, err := probeBytes(t, data); !errors.Is(err, ErrUnknownFormat) {
+			t.Errorf("Probe(%q) error = %v, want ErrUnknownFormat", data, err)
+		}
+	}
+}
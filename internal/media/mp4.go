@@ -0,0 +1,219 @@
+package media
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"time"
+)
+
+// maxMoov caps how much of a file's moov box is read into memory. A
+// recording's sample tables grow with its length; an hour of video is a
+// few MB.
+const maxMoov = 64 << 20
+
+// mp4Epoch is where MP4 timestamps count from.
+var mp4Epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// isTopLevelBox reports whether typ can start an MP4 or QuickTime file.
+func isTopLevelBox(typ string) bool {
+	switch typ {
+	case "ftyp", "moov", "mdat", "free", "skip", "wide":
+		return true
+	}
+	return false
+}
+
+// probeMP4 finds the moov box, which may come before or after the media
+// data, and reads the movie and first video track headers from it.
+func probeMP4(r io.ReadSeeker) (Info, error) {
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return Info{}, err
+	}
+	for off := int64(0); off+8 <= end; {
+		if _, err := r.Seek(off, io.SeekStart); err != nil {
+			return Info{}, err
+		}
+		var h [16]byte
+		if _, err := io.ReadFull(r, h[:8]); err != nil {
+			return Info{}, err
+		}
+		size, hdr := int64(binary.BigEndian.Uint32(h[:4])), int64(8)
+		switch size {
+		case 0:
+			size = end - off
+		case 1:
+			if _, err := io.ReadFull(r, h[8:16]); err != nil {
+				return Info{}, err
+			}
+			size, hdr = int64(binary.BigEndian.Uint64(h[8:16])), 16
+		}
+		if size < hdr || off+size > end {
+			break // truncated, e.g. a recording that was cut off
+		}
+		if string(h[4:8]) == "moov" {
+			if size-hdr > maxMoov {
+				return Info{}, errors.New("mp4: moov box too large")
+			}
+			moov := make([]byte, size-hdr)
+			if _, err := io.ReadFull(r, moov); err != nil {
+				return Info{}, err
+			}
+			return parseMoov(moov), nil
+		}
+		off += size
+	}
+	return Info{}, errors.New("mp4: no moov box")
+}
+
+func parseMoov(moov []byte) Info {
+	var info Info
+	eachBox(moov, func(typ string, p []byte) {
+		switch typ {
+		case "mvhd":
+			info.CapturedAt, info.Duration = parseMvhd(p)
+		case "trak":
+			if info.Codec == "" {
+				parseTrak(p, &info)
+			}
+		}
+	})
+	return info
+}
+
+// parseMvhd returns the movie's creation time and duration.
+func parseMvhd(p []byte) (time.Time, time.Duration) {
+	var created, timescale, duration uint64
+	switch {
+	case len(p) >= 32 && p[0] == 1:
+		created = binary.BigEndian.Uint64(p[4:12])
+		timescale = uint64(binary.BigEndian.Uint32(p[20:24]))
+		duration = binary.BigEndian.Uint64(p[24:32])
+	case len(p) >= 20 && p[0] == 0:
+		created = uint64(binary.BigEndian.Uint32(p[4:8]))
+		timescale = uint64(binary.BigEndian.Uint32(p[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(p[16:20]))
+	default:
+		return time.Time{}, 0
+	}
+	var t time.Time
+	// Unset, or counted from 1970 by a broken muxer.
+	if unix := int64(created) - int64(-mp4Epoch.Unix()); created != 0 && unix > 0 {
+		t = time.Unix(unix, 0)
+	}
+	var d time.Duration
+	if timescale > 0 && duration != ^uint64(0) && duration != uint64(^uint32(0)) {
+		d = time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+	}
+	return t, d
+}
+
+// parseTrak fills in the dimensions and codec if trak is a video track.
+func parseTrak(trak []byte, info *Info) {
+	var width, height int
+	var video bool
+	var codec string
+	var entryW, entryH int
+	eachBox(trak, func(typ string, p []byte) {
+		switch typ {
+		case "tkhd":
+			width, height = parseTkhd(p)
+		case "mdia":
+			eachBox(p, func(typ string, p []byte) {
+				switch typ {
+				case "hdlr":
+					video = len(p) >= 12 && string(p[8:12]) == "vide"
+				case "minf":
+					if stsd := findBox(p, "stbl", "stsd"); len(stsd) >= 16 {
+						entry := stsd[8:]
+						codec = string(entry[4:8])
+						// Visual sample entries carry 16-bit dimensions too.
+						if len(entry) >= 36 {
+							entryW = int(binary.BigEndian.Uint16(entry[32:34]))
+							entryH = int(binary.BigEndian.Uint16(entry[34:36]))
+						}
+					}
+				}
+			})
+		}
+	})
+	if !video {
+		return
+	}
+	if width == 0 || height == 0 {
+		width, height = entryW, entryH
+	}
+	info.Width, info.Height = width, height
+	info.Codec = codecName(codec)
+}
+
+// parseTkhd returns a track's display size, stored as 16.16 fixed point.
+func parseTkhd(p []byte) (int, int) {
+	off := 76
+	if len(p) > 0 && p[0] == 1 {
+		off = 88
+	}
+	if len(p) < off+8 {
+		return 0, 0
+	}
+	return int(binary.BigEndian.Uint32(p[off:]) >> 16), int(binary.BigEndian.Uint32(p[off+4:]) >> 16)
+}
+
+func codecName(fourcc string) string {
+	switch fourcc {
+	case "avc1", "avc3":
+		return "h264"
+	case "hvc1", "hev1":
+		return "hevc"
+	case "av01":
+		return "av1"
+	case "vp08":
+		return "vp8"
+	case "vp09":
+		return "vp9"
+	case "mp4v":
+		return "mpeg4"
+	}
+	return fourcc
+}
+
+// findBox follows path down from the boxes in b and returns the payload
+// of the last one, or nil.
+func findBox(b []byte, path ...string) []byte {
+	for _, want := range path {
+		var found []byte
+		eachBox(b, func(typ string, p []byte) {
+			if found == nil && typ == want {
+				found = p
+			}
+		})
+		if found == nil {
+			return nil
+		}
+		b = found
+	}
+	return b
+}
+
+// eachBox calls fn with the type and payload of each box in b. A
+// truncated box ends the walk.
+func eachBox(b []byte, fn func(typ string, payload []byte)) {
+	for len(b) >= 8 {
+		size, hdr := uint64(binary.BigEndian.Uint32(b[:4])), uint64(8)
+		switch size {
+		case 0:
+			size = uint64(len(b))
+		case 1:
+			if len(b) < 16 {
+				return
+			}
+			size, hdr = binary.BigEndian.Uint64(b[8:16]), 16
+		}
+		if size < hdr || size > uint64(len(b)) {
+			return
+		}
+		fn(string(b[4:8]), b[hdr:size])
+		b = b[size:]
+	}
+}
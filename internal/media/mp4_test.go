@@ -0,0 +1,168 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"time"
+)
+
+// box builds an MP4 box with a 32-bit size.
+func box(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := binary.BigEndian.AppendUint32(nil, uint32(8+len(body)))
+	return append(append(b, typ...), body...)
+}
+
+// largeBox builds an MP4 box with a 64-bit size.
+func largeBox(typ string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := binary.BigEndian.AppendUint32(nil, 1)
+	b = append(b, typ...)
+	b = binary.BigEndian.AppendUint64(b, uint64(16+len(body)))
+	return append(b, body...)
+}
+
+// mp4Time is t in seconds since the MP4 epoch.
+func mp4Time(t time.Time) uint64 {
+	return uint64(t.Unix() - mp4Epoch.Unix())
+}
+
+func mvhdV0(created time.Time, timescale, duration uint32) []byte {
+	p := make([]byte, 100)
+	binary.BigEndian.PutUint32(p[4:], uint32(mp4Time(created)))
+	binary.BigEndian.PutUint32(p[8:], uint32(mp4Time(created)))
+	binary.BigEndian.PutUint32(p[12:], timescale)
+	binary.BigEndian.PutUint32(p[16:], duration)
+	return box("mvhd", p)
+}
+
+func mvhdV1(created time.Time, timescale uint32, duration uint64) []byte {
+	p := make([]byte, 112)
+	p[0] = 1
+	binary.BigEndian.PutUint64(p[4:], mp4Time(created))
+	binary.BigEndian.PutUint64(p[12:], mp4Time(created))
+	binary.BigEndian.PutUint32(p[20:], timescale)
+	binary.BigEndian.PutUint64(p[24:], duration)
+	return box("mvhd", p)
+}
+
+// tkhd builds a track header with width and height in 16.16 fixed point.
+func tkhd(version byte, width, height int) []byte {
+	off := 76
+	if version == 1 {
+		off = 88
+	}
+	p := make([]byte, off+8)
+	p[0] = version
+	binary.BigEndian.PutUint32(p[off:], uint32(width)<<16)
+	binary.BigEndian.PutUint32(p[off+4:], uint32(height)<<16)
+	return box("tkhd", p)
+}
+
+// trak builds a track with the given handler and a single sample entry,
+// whose visual dimensions are width and height.
+func trak(header []byte, handler, fourcc string, width, height int) []byte {
+	hdlr := make([]byte, 24)
+	copy(hdlr[8:], handler)
+	entry := make([]byte, 78)
+	binary.BigEndian.PutUint16(entry[24:], uint16(width))
+	binary.BigEndian.PutUint16(entry[26:], uint16(height))
+	stsd := box("stsd", []byte{0, 0, 0, 0, 0, 0, 0, 1}, box(fourcc, entry))
+	return box("trak", header, box("mdia", box("hdlr", hdlr), box("minf", box("stbl", stsd))))
+}
+
+var ftyp = box("ftyp", []byte("isom\x00\x00\x02\x00isomiso2mp41"))
+
+func TestProbeMP4(t *testing.T) {
+	recorded := time.Date(2025, 3, 14, 15, 9, 26, 0, time.UTC)
+	video := trak(tkhd(0, 1920, 1080), "vide", "hvc1", 1920, 1080)
+	audio := trak(tkhd(0, 0, 0), "soun", "mp4a", 0, 0)
+	mdat := box("mdat", make([]byte, 4096))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		want    Info
+		wantErr bool
+	}{
+		{
+			name: "mvhd v0",
+			data: bytes.Join([][]byte{ftyp, box("moov", mvhdV0(recorded, 1000, 90500), video), mdat}, nil),
+			want: Info{CapturedAt: recorded, Duration: 90500 * time.Millisecond, Width: 1920, Height: 1080, Codec: "hevc"},
+		},
+		{
+			name: "mvhd v1",
+			data: bytes.Join([][]byte{ftyp, box("moov", mvhdV1(recorded, 90000, 90000*30), video), mdat}, nil),
+			want: Info{CapturedAt: recorded, Duration: 30 * time.Second, Width: 1920, Height: 1080, Codec: "hevc"},
+		},
+		{
+			name: "moov after mdat",
+			data: bytes.Join([][]byte{ftyp, mdat, box("moov", mvhdV0(recorded, 600, 1200), video)}, nil),
+			want: Info{CapturedAt: recorded, Duration: 2 * time.Second, Width: 1920, Height: 1080, Codec: "hevc"},
+		},
+		{
+			name: "64-bit box sizes",
+			data: bytes.Join([][]byte{ftyp, largeBox("mdat", make([]byte, 4096)), largeBox("moov", mvhdV0(recorded, 1000, 5000), video)}, nil),
+			want: Info{CapturedAt: recorded, Duration: 5 * time.Second, Width: 1920, Height: 1080, Codec: "hevc"},
+		},
+		{
+			name: "mdat runs to end of file",
+			data: bytes.Join([][]byte{ftyp, box("moov", mvhdV0(recorded, 1000, 5000), video), {0, 0, 0, 0}, []byte("mdat"), make([]byte, 64)}, nil),
+			want: Info{CapturedAt: recorded, Duration: 5 * time.Second, Width: 1920, Height: 1080, Codec: "hevc"},
+		},
+		{
+			name: "audio track first",
+			data: bytes.Join([][]byte{ftyp, box("moov", mvhdV0(recorded, 1000, 5000), audio, trak(tkhd(0, 1280, 720), "vide", "avc1", 1280, 720)), mdat}, nil),
+			want: Info{CapturedAt: recorded, Duration: 5 * time.Second, Width: 1280, Height: 720, Codec: "h264"},
+		},
+		{
+			name: "tkhd v1",
+			data: bytes.Join([][]byte{ftyp, box("moov", mvhdV1(recorded, 1000, 5000), trak(tkhd(1, 3840, 2160), "vide", "av01", 3840, 2160)), mdat}, nil),
+			want: Info{CapturedAt: recorded, Duration: 5 * time.Second, Width: 3840, Height: 2160, Codec: "av1"},
+		},
+		{
+			name: "dimensions from sample entry",
+			data: bytes.Join([][]byte{ftyp, box("moov", mvhdV0(recorded, 1000, 5000), trak(tkhd(0, 0, 0), "vide", "avc1", 1024, 1024)), mdat}, nil),
+			want: Info{CapturedAt: recorded, Duration: 5 * time.Second, Width: 1024, Height: 1024, Codec: "h264"},
+		},
+		{
+			name: "unset creation time and duration",
+			data: bytes.Join([][]byte{ftyp, box("moov", mvhdV0(mp4Epoch, 1000, 0xFFFFFFFF), video), mdat}, nil),
+			want: Info{Width: 1920, Height: 1080, Codec: "hevc"},
+		},
+		{
+			name: "unknown codec kept as fourcc",
+			data: bytes.Join([][]byte{ftyp, box("moov", mvhdV0(recorded, 1000, 5000), trak(tkhd(0, 640, 480), "vide", "mjpa", 640, 480)), mdat}, nil),
+			want: Info{CapturedAt: recorded, Duration: 5 * time.Second, Width: 640, Height: 480, Codec: "mjpa"},
+		},
+		{
+			name:    "truncated recording",
+			data:    bytes.Join([][]byte{ftyp, mdat[:2048]}, nil),
+			wantErr: true,
+		},
+		{
+			name:    "truncated moov",
+			data:    bytes.Join([][]byte{ftyp, mdat, box("moov", mvhdV0(recorded, 1000, 5000), video)[:64]}, nil),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := probeBytes(t, tt.data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Probe = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.CapturedAt.Equal(tt.want.CapturedAt) || got.Duration != tt.want.Duration ||
+				got.Width != tt.want.Width || got.Height != tt.want.Height || got.Codec != tt.want.Codec {
+				t.Errorf("Probe = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
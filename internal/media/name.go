@@ -0,0 +1,23 @@
+package media
+
+import (
+	"regexp"
+	"time"
+)
+
+// Quest captures are named <package>-<yyyymmdd>-<hhmmss>.<ext>, sometimes
+// with more after the time.
+var captureName = regexp.MustCompile(`^([A-Za-z]\w*(?:\.\w+)+)-(\d{8}-\d{6})`)
+
+// ParseName returns the package of the app a Quest capture was taken in,
+// and when, from its file name. ok is false for other names. The time is
+// the headset's local time, read as this computer's; it is zero if the
+// digits aren't a valid date.
+func ParseName(name string) (app string, at time.Time, ok bool) {
+	m := captureName.FindStringSubmatch(name)
+	if m == nil {
+		return "", time.Time{}, false
+	}
+	at, _ = time.ParseInLocation("20060102-150405", m[2], time.Local)
+	return m[1], at, true
+}
@@ -2,6 +2,8 @@ package rclone
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -66,6 +68,25 @@ func (c *Client) CopyFrom(remoteSrc, localDest string) error {
 	return nil
 }
 
+// Move moves a single remote file to dst on the same remote, server-side
+// where the backend supports it.
+func (c *Client) Move(src, dst string) error {
+	out, err := newCmd(c.bin, "moveto", src, dst).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rclone moveto %s: %w\n%s", src, err, out)
+	}
+	return nil
+}
+
+// Delete removes a single remote file.
+func (c *Client) Delete(remoteFile string) error {
+	out, err := newCmd(c.bin, "deletefile", remoteFile).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rclone deletefile %s: %w\n%s", remoteFile, err, out)
+	}
+	return nil
+}
+
 // excludeFlags returns rclone flags to skip macOS/Windows junk files.
 var excludeFlags = []string{
 	"--exclude", ".DS_Store",
@@ -85,15 +106,114 @@ func (c *Client) CopyDir(localDir, dest string) error {
 	return nil
 }
 
-// Check verifies a file exists at the destination.
-func (c *Client) Check(localPath, dest string) error {
-	out, err := newCmd(c.bin, "check", "--one-way",
-		"--include", localPath, ".", dest,
-	).CombinedOutput()
+var (
+	// ErrNotFound means the remote object doesn't exist.
+	ErrNotFound = errors.New("not found on destination")
+	// ErrMismatch means the remote object's size or hash is wrong.
+	ErrMismatch = errors.New("destination copy differs")
+)
+
+// Object is a remote file as reported by `rclone lsjson`.
+type Object struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	SHA256  string // "" if the backend doesn't store SHA-256
+	ID      string // backend object ID, "" if the backend has none
+}
+
+type lsjsonItem struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+	Hashes  map[string]string
+	ID      string
+}
+
+// Stat looks up a single remote file, including its SHA-256 where the
+// backend can report one without downloading the file.
+func (c *Client) Stat(remoteFile string) (Object, error) {
+	out, err := newCmd(c.bin, "lsjson", "--hash", "--hash-type", "sha256", "--files-only", remoteFile).Output()
 	if err != nil {
-		return fmt.Errorf("rclone check: %w\n%s", err, out)
+		var stderr []byte
+		if ee, ok := err.(*exec.ExitError); ok {
+			stderr = ee.Stderr
+		}
+		if strings.Contains(strings.ToLower(string(stderr)), "not found") {
+			return Object{}, fmt.Errorf("%s: %w", remoteFile, ErrNotFound)
+		}
+		return Object{}, fmt.Errorf("rclone lsjson %s: %w\n%s", remoteFile, err, stderr)
 	}
-	return nil
+	var items []lsjsonItem
+	if err := json.Unmarshal(out, &items); err != nil {
+		return Object{}, fmt.Errorf("rclone lsjson %s: %w", remoteFile, err)
+	}
+	if len(items) != 1 || items[0].IsDir {
+		return Object{}, fmt.Errorf("%s: %w", remoteFile, ErrNotFound)
+	}
+	return items[0].object(), nil
+}
+
+// List returns every file under remote, recursively, with paths relative
+// to remote. With hashes, SHA-256 is requested too; on backends that
+// don't store hashes (local disks, SMB) this means reading every file.
+func (c *Client) List(remote string, hashes bool) ([]Object, error) {
+	args := []string{"lsjson", "-R", "--files-only"}
+	if hashes {
+		args = append(args, "--hash", "--hash-type", "sha256")
+	}
+	args = append(args, excludeFlags...)
+	args = append(args, remote)
+	out, err := newCmd(c.bin, args...).Output()
+	if err != nil {
+		var stderr []byte
+		if ee, ok := err.(*exec.ExitError); ok {
+			stderr = ee.Stderr
+		}
+		if strings.Contains(strings.ToLower(string(stderr)), "not found") {
+			return nil, fmt.Errorf("%s: %w", remote, ErrNotFound)
+		}
+		return nil, fmt.Errorf("rclone lsjson %s: %w\n%s", remote, err, stderr)
+	}
+	var items []lsjsonItem
+	if err := json.Unmarshal(out, &items); err != nil {
+		return nil, fmt.Errorf("rclone lsjson %s: %w", remote, err)
+	}
+	objs := make([]Object, 0, len(items))
+	for _, it := range items {
+		if !it.IsDir {
+			objs = append(objs, it.object())
+		}
+	}
+	return objs, nil
+}
+
+func (it lsjsonItem) object() Object {
+	return Object{
+		Path:    it.Path,
+		Size:    it.Size,
+		ModTime: it.ModTime,
+		SHA256:  strings.ToLower(it.Hashes["sha256"]),
+		ID:      it.ID,
+	}
+}
+
+// Check verifies that remoteFile exists with the given size and, where the
+// backend reports SHA-256 and sha256 is non-empty, the given hash. It
+// returns the object as the destination reports it.
+func (c *Client) Check(remoteFile string, size int64, sha256 string) (Object, error) {
+	obj, err := c.Stat(remoteFile)
+	if err != nil {
+		return Object{}, err
+	}
+	if obj.Size != size {
+		return Object{}, fmt.Errorf("%s: %w (%d bytes, expected %d)", remoteFile, ErrMismatch, obj.Size, size)
+	}
+	if sha256 != "" && obj.SHA256 != "" && obj.SHA256 != sha256 {
+		return Object{}, fmt.Errorf("%s: %w (SHA-256 %s, expected %s)", remoteFile, ErrMismatch, obj.SHA256, sha256)
+	}
+	return obj, nil
 }
 
 // IsReachable checks if a remote destination is reachable with a short timeout.
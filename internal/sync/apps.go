@@ -0,0 +1,180 @@
+package sync
+
+import (
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/media"
+)
+
+// AppPackage returns the package of the app e was captured in, or
+// "Unknown".
+func AppPackage(e manifest.Entry) string {
+	if e.App != "" {
+		return e.App
+	}
+	if app, _, ok := media.ParseName(path.Base(e.RemotePath)); ok {
+		return app
+	}
+	return "Unknown"
+}
+
+// AppName returns the label of the app e was captured in, falling back to
+// its package.
+func AppName(e manifest.Entry) string {
+	if e.AppLabel != "" {
+		return e.AppLabel
+	}
+	return AppPackage(e)
+}
+
+// withApp fills in e's app and its cached label, for entries that didn't
+// come from the manifest.
+func withApp(db *manifest.DB, e manifest.Entry) (manifest.Entry, error) {
+	if e.AppLabel != "" {
+		return e, nil
+	}
+	if e.App == "" {
+		e.App, _, _ = media.ParseName(path.Base(e.RemotePath))
+		if e.App == "" {
+			return e, nil
+		}
+	}
+	label, err := db.AppLabel(e.App)
+	if err != nil {
+		return e, err
+	}
+	e.AppLabel = label
+	return e, nil
+}
+
+// AppFilter matches files by the app they were captured in. A nil filter
+// matches everything.
+type AppFilter map[string]bool // lower-cased package names
+
+// NewAppFilter builds a filter for apps given by package name or label,
+// ignoring case. No apps gives a nil filter.
+func NewAppFilter(db *manifest.DB, apps []string) (AppFilter, error) {
+	if len(apps) == 0 {
+		return nil, nil
+	}
+	known, err := db.Apps()
+	if err != nil {
+		return nil, err
+	}
+	f := make(AppFilter)
+	for _, want := range apps {
+		want = strings.TrimSpace(want)
+		f[strings.ToLower(want)] = true
+		for _, a := range known {
+			if a.Label != "" && strings.EqualFold(a.Label, want) {
+				f[strings.ToLower(a.Package)] = true
+			}
+		}
+	}
+	return f, nil
+}
+
+// Match reports whether e was captured in one of the filter's apps.
+func (f AppFilter) Match(e manifest.Entry) bool {
+	if f == nil {
+		return true
+	}
+	return f[strings.ToLower(AppPackage(e))]
+}
+
+// Filter returns the entries the filter matches.
+func (f AppFilter) Filter(entries []manifest.Entry) []manifest.Entry {
+	if f == nil {
+		return entries
+	}
+	var out []manifest.Entry
+	for _, e := range entries {
+		if f.Match(e) {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// RefreshAppLabels asks the headset for the labels of apps that captures
+// in the manifest were taken in and that are installed on it, and caches
+// them. Apps that already have a label are skipped unless all is set;
+// labels the user set are never replaced. It returns how many labels were
+// learned.
+func RefreshAppLabels(adbClient *adb.Client, db *manifest.DB, d adb.Device, all bool) (int, error) {
+	apps, err := db.Apps()
+	if err != nil {
+		return 0, err
+	}
+	var wanted []string
+	for _, a := range apps {
+		if a.Files > 0 && a.Source != manifest.LabelFromUser && (all || a.Label == "") {
+			wanted = append(wanted, a.Package)
+		}
+	}
+	return labelApps(adbClient, db, d, wanted)
+}
+
+// labelNewApps caches labels for the apps files on the headset were
+// captured in that don't have one yet, so a pull can file them by name.
+func labelNewApps(adbClient *adb.Client, db *manifest.DB, d adb.Device, files []adb.FileInfo) error {
+	seen := make(map[string]bool)
+	var wanted []string
+	for _, f := range files {
+		app, _, ok := media.ParseName(path.Base(f.Path))
+		if !ok || seen[app] {
+			continue
+		}
+		seen[app] = true
+		label, err := db.AppLabel(app)
+		if err != nil {
+			return err
+		}
+		if label == "" {
+			wanted = append(wanted, app)
+		}
+	}
+	_, err := labelApps(adbClient, db, d, wanted)
+	return err
+}
+
+// labelApps looks up the labels of the packages that are installed on d and
+// caches them.
+func labelApps(adbClient *adb.Client, db *manifest.DB, d adb.Device, pkgs []string) (int, error) {
+	if len(pkgs) == 0 {
+		return 0, nil
+	}
+	installed, err := adbClient.Packages(d.Serial)
+	if err != nil {
+		return 0, err
+	}
+	onDevice := make(map[string]bool, len(installed))
+	for _, p := range installed {
+		onDevice[p] = true
+	}
+
+	n := 0
+	var errs []error
+	for _, pkg := range pkgs {
+		if !onDevice[pkg] {
+			continue
+		}
+		label, err := adbClient.AppLabel(d.Serial, pkg)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if label == "" {
+			continue
+		}
+		if err := db.SetAppLabel(pkg, label, manifest.LabelFromHeadset); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, errors.Join(errs...)
+}
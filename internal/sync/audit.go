@@ -0,0 +1,117 @@
+package sync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+)
+
+// Audit compares what a destination actually holds with what the manifest
+// says was synced there.
+type Audit struct {
+	Destination string
+	OK          []manifest.SyncedEntry // present with the right size (and hash, if checked)
+	Missing     []manifest.SyncedEntry // recorded as synced but not on the destination
+	Damaged     []Damaged              // present, but the size or hash is wrong
+	Unknown     []rclone.Object        // on the destination but not in the manifest
+}
+
+// Damaged is a destination copy that doesn't match the manifest.
+type Damaged struct {
+	manifest.SyncedEntry
+	Object rclone.Object
+	Reason string
+}
+
+// AuditDest lists dest with rclone and checks every dest_syncs row for it.
+// With hashes, SHA-256 is compared too where both sides have one.
+func AuditDest(rc *rclone.Client, db *manifest.DB, cfg *config.Config, dest config.Destination, hashes bool) (Audit, error) {
+	audit := Audit{Destination: dest.Name}
+	objs, err := rc.List(dest.RcloneRemote, hashes)
+	if err != nil {
+		return audit, err
+	}
+	remote := make(map[string]rclone.Object, len(objs))
+	for _, o := range objs {
+		remote[o.Path] = o
+	}
+
+	syncDir := cfg.ExpandSyncDir()
+	known := make(map[string]bool)
+	all, err := db.AllFiles()
+	if err != nil {
+		return audit, err
+	}
+	for _, e := range all {
+		known[DestObjectPath(cfg, dest, syncDir, e)] = true
+	}
+
+	synced, err := db.GetDestSyncedFiles(dest.Name)
+	if err != nil {
+		return audit, err
+	}
+	for _, e := range synced {
+		objectPath := SyncedObjectPath(syncDir, e)
+		known[objectPath] = true
+		o, ok := remote[objectPath]
+		switch {
+		case !ok:
+			audit.Missing = append(audit.Missing, e)
+		case o.Size != e.Size:
+			audit.Damaged = append(audit.Damaged, Damaged{e, o,
+				fmt.Sprintf("%d bytes, expected %d", o.Size, e.Size)})
+		case hashes && o.SHA256 != "" && e.SHA256 != "" && o.SHA256 != e.SHA256:
+			audit.Damaged = append(audit.Damaged, Damaged{e, o,
+				fmt.Sprintf("SHA-256 %s, expected %s", o.SHA256, e.SHA256)})
+		default:
+			e.Sync.ObjectPath = objectPath
+			e.Sync.Size = o.Size
+			if o.SHA256 != "" {
+				e.Sync.SHA256 = o.SHA256
+			}
+			if o.ID != "" {
+				e.Sync.ObjectID = o.ID
+			}
+			audit.OK = append(audit.OK, e)
+		}
+	}
+
+	for _, o := range objs {
+		if strings.HasPrefix(o.Path, ".fetchquest/") || known[o.Path] {
+			continue
+		}
+		audit.Unknown = append(audit.Unknown, o)
+	}
+	return audit, nil
+}
+
+// Repair forgets the syncs of missing and damaged files, so the next push
+// uploads them again, and marks copies found intact as verified. It
+// returns the number of files queued for upload.
+func (a Audit) Repair(db *manifest.DB) (int, error) {
+	queued := 0
+	for _, e := range a.Missing {
+		if err := db.DeleteDestSync(e.ID, a.Destination); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	for _, d := range a.Damaged {
+		if err := db.DeleteDestSync(d.ID, a.Destination); err != nil {
+			return queued, err
+		}
+		queued++
+	}
+	for _, e := range a.OK {
+		if e.Sync.VerifiedAt != nil {
+			continue
+		}
+		if err := db.MarkVerified(e.Sync); err != nil {
+			return queued, err
+		}
+	}
+	return queued, nil
+}
@@ -0,0 +1,163 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+)
+
+// Daemon defaults.
+const (
+	DefaultCheckInterval = time.Minute      // re-check reachable destinations for new work
+	DefaultMinBackoff    = 30 * time.Second // first retry after a destination goes away
+	DefaultMaxBackoff    = 30 * time.Minute
+	// HeartbeatStale is how old a daemon heartbeat may be before the
+	// daemon is considered gone.
+	HeartbeatStale = 3 * daemonTick
+)
+
+const daemonTick = 10 * time.Second
+
+// Daemon keeps pushing queued files to destinations. Unreachable
+// destinations are re-probed with exponential backoff and drained as soon
+// as they come back. Progress is recorded in the manifest (dest_status,
+// daemon_status) so other processes can show what is still queued.
+type Daemon struct {
+	Rclone   *rclone.Client
+	Manifest *manifest.DB
+	// LoadConfig is called each round so destinations added while the
+	// daemon runs are picked up. Defaults to config.Load.
+	LoadConfig func() (*config.Config, error)
+
+	CheckInterval time.Duration
+	MinBackoff    time.Duration
+	MaxBackoff    time.Duration
+
+	// AfterPush, if set, runs after a round that pushed at least one file.
+	AfterPush func(cfg *config.Config)
+}
+
+type destState struct {
+	next    time.Time
+	backoff time.Duration
+}
+
+// Run loops until ctx is cancelled.
+func (d *Daemon) Run(ctx context.Context) error {
+	load := d.LoadConfig
+	if load == nil {
+		load = config.Load
+	}
+	interval := orDefault(d.CheckInterval, DefaultCheckInterval)
+	minBackoff := orDefault(d.MinBackoff, DefaultMinBackoff)
+	maxBackoff := orDefault(d.MaxBackoff, DefaultMaxBackoff)
+
+	pid := os.Getpid()
+	started := time.Now()
+	defer d.Manifest.ClearDaemon(pid)
+
+	states := make(map[string]*destState)
+	ticker := time.NewTicker(daemonTick)
+	defer ticker.Stop()
+	for {
+		if err := d.Manifest.RecordDaemonHeartbeat(pid, started); err != nil {
+			return err
+		}
+		cfg, err := load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: load config: %v\n", err)
+		} else {
+			pushed := 0
+			now := time.Now()
+			for _, dest := range cfg.Destinations {
+				if ctx.Err() != nil {
+					break
+				}
+				st := states[dest.Name]
+				if st == nil {
+					st = &destState{}
+					states[dest.Name] = st
+				}
+				if now.Before(st.next) {
+					continue
+				}
+				pushed += d.service(cfg, dest, st, interval, minBackoff, maxBackoff)
+			}
+			if pushed > 0 && d.AfterPush != nil {
+				d.AfterPush(cfg)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// service probes one destination and drains its queue if it is reachable.
+// It returns the number of files pushed.
+func (d *Daemon) service(cfg *config.Config, dest config.Destination, st *destState, interval, minBackoff, maxBackoff time.Duration) int {
+	queued, err := d.Manifest.CountQueued(dest.Name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 0
+	}
+
+	if !d.Rclone.IsReachable(dest.RcloneRemote) {
+		if st.backoff == 0 {
+			st.backoff = minBackoff
+		} else if st.backoff *= 2; st.backoff > maxBackoff {
+			st.backoff = maxBackoff
+		}
+		st.next = time.Now().Add(st.backoff)
+		_ = d.Manifest.RecordDestProbe(dest.Name, false, "destination unreachable", st.next)
+		if queued > 0 {
+			fmt.Printf("[%s] %s unreachable, %d file%s queued; retrying in %s\n",
+				time.Now().Format("15:04:05"), dest.Name, queued, plural(queued), st.backoff)
+		}
+		return 0
+	}
+
+	st.backoff = 0
+	st.next = time.Now().Add(interval)
+	_ = d.Manifest.RecordDestProbe(dest.Name, true, "", time.Time{})
+	if queued == 0 {
+		return 0
+	}
+
+	fmt.Printf("[%s] %s reachable, pushing %d queued file%s\n",
+		time.Now().Format("15:04:05"), dest.Name, queued, plural(queued))
+	p := &Pusher{Rclone: d.Rclone, Manifest: d.Manifest, Config: cfg}
+	r, err := p.PushToDest(dest)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "  Error: %v\n", err)
+	}
+	for _, e := range r.Errors {
+		fmt.Fprintf(os.Stderr, "  Error: %s\n", e)
+	}
+	if r.FilesPushed > 0 {
+		_ = d.Manifest.RecordDestPushed(dest.Name)
+	}
+	return r.FilesPushed
+}
+
+func orDefault(d, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
@@ -0,0 +1,121 @@
+package sync
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+)
+
+// PartialSuffix marks a file that is still being pulled.
+const PartialSuffix = ".partial"
+
+// StalePartialAge is how long a partial file may sit untouched before a
+// pull run removes it. Younger partials may belong to a pull that is still
+// running in another process.
+const StalePartialAge = time.Hour
+
+// FetchFile pulls f from the device to localPath and returns its SHA-256.
+// Data is written to localPath+PartialSuffix and only renamed into place
+// once its size matches f.Size and its hash matches sha256sum on the
+// device, so an interrupted or corrupted pull never leaves a bad file where
+// a good one is expected. A partial left behind by an earlier run is
+// resumed. Quest captures are only ever appended to, so a partial that is
+// shorter than the file on the device is a prefix of it.
+func FetchFile(ctx context.Context, a *adb.Client, serial string, f adb.FileInfo, localPath string, progress func(written int64)) (string, error) {
+	partial := localPath + PartialSuffix
+
+	var have int64
+	if info, err := os.Stat(partial); err == nil {
+		have = info.Size()
+	}
+	var err error
+	switch {
+	case have == f.Size && have > 0:
+		// Fully transferred last time but never verified.
+		if progress != nil {
+			progress(have)
+		}
+	case have > 0 && have < f.Size:
+		err = a.PullFrom(ctx, serial, f.Path, partial, have, progress)
+		if err != nil && ctx.Err() == nil {
+			// Resume isn't possible (no tail on the device, or the file was
+			// replaced): start over.
+			err = a.PullCtx(ctx, serial, f.Path, partial, progress)
+		}
+	default:
+		err = a.PullCtx(ctx, serial, f.Path, partial, progress)
+	}
+	if err != nil {
+		// Leave the partial for the next run to resume.
+		return "", err
+	}
+
+	info, err := os.Stat(partial)
+	if err != nil {
+		return "", fmt.Errorf("verify %s: %w", f.Path, err)
+	}
+	if info.Size() != f.Size {
+		os.Remove(partial)
+		return "", fmt.Errorf("verify %s: pulled %d bytes, expected %d", f.Path, info.Size(), f.Size)
+	}
+	want, err := a.SHA256(ctx, serial, f.Path)
+	if err != nil {
+		return "", err
+	}
+	got, err := HashFile(partial)
+	if err != nil {
+		return "", fmt.Errorf("verify %s: %w", f.Path, err)
+	}
+	if got != want {
+		os.Remove(partial)
+		return "", fmt.Errorf("verify %s: SHA-256 mismatch (device %s, pulled %s)", f.Path, want, got)
+	}
+	if err := os.Rename(partial, localPath); err != nil {
+		return "", fmt.Errorf("rename %s: %w", partial, err)
+	}
+	return got, nil
+}
+
+// HashFile returns the hex SHA-256 of a local file.
+func HashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// CleanPartials removes partial files under dir, at any depth, that
+// haven't been written to for StalePartialAge. It returns the number
+// removed.
+func CleanPartials(dir string) int {
+	removed := 0
+	filepath.WalkDir(dir, func(p string, e fs.DirEntry, err error) error {
+		if err != nil || e.IsDir() || !strings.HasSuffix(e.Name(), PartialSuffix) {
+			return nil
+		}
+		info, err := e.Info()
+		if err != nil || time.Since(info.ModTime()) < StalePartialAge {
+			return nil
+		}
+		if os.Remove(p) == nil {
+			removed++
+		}
+		return nil
+	})
+	return removed
+}
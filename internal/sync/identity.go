@@ -0,0 +1,41 @@
+package sync
+
+import (
+	"fmt"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+)
+
+// AdoptDeviceIdentities moves manifest rows and config entries that were
+// recorded under a transport serial (such as 192.168.1.42:5555) over to the
+// headset's hardware serial. Older versions keyed devices by transport, so a
+// Quest used over WiFi ended up tracked twice.
+// It reports whether cfg was modified and needs saving.
+func AdoptDeviceIdentities(db *manifest.DB, cfg *config.Config, devs []adb.Device) (bool, error) {
+	changed := false
+	for _, d := range devs {
+		if d.ID == "" || d.ID == d.Serial {
+			continue
+		}
+		if _, err := db.RenameDevice(d.Serial, d.ID); err != nil {
+			return changed, fmt.Errorf("migrate %s to %s: %w", d.Serial, d.ID, err)
+		}
+		old, ok := cfg.Devices[d.Serial]
+		if !ok {
+			continue
+		}
+		dc := cfg.Devices[d.ID]
+		if dc.Nickname == "" {
+			dc.Nickname = old.Nickname
+		}
+		if dc.WiFiIP == "" {
+			dc.WiFiIP = old.WiFiIP
+		}
+		cfg.Devices[d.ID] = dc
+		delete(cfg.Devices, d.Serial)
+		changed = true
+	}
+	return changed, nil
+}
@@ -0,0 +1,326 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+)
+
+// ImportMode is how Import puts files into the sync dir.
+type ImportMode string
+
+const (
+	ImportCopy ImportMode = "copy"
+	ImportMove ImportMode = "move"
+	ImportLink ImportMode = "link" // hard link; the folder must be on the same filesystem
+)
+
+// ImportDevice is the device key for imported files that aren't on any
+// headset and no --device was given.
+const ImportDevice = "import"
+
+var importExts = map[string]string{
+	".mp4": "Videos", ".mov": "Videos", ".mkv": "Videos", ".webm": "Videos",
+	".jpg": "Screenshots", ".jpeg": "Screenshots", ".png": "Screenshots", ".heic": "Screenshots",
+}
+
+// MediaType returns the folder a manifest entry's media type is kept in:
+// Videos, Screenshots, Photos or Other.
+func MediaType(e manifest.Entry) string {
+	if manifest.IsImported(e.RemotePath) {
+		if t, ok := importExts[strings.ToLower(path.Ext(e.RemotePath))]; ok {
+			return t
+		}
+		return "Other"
+	}
+	return mediaTypeFromPath(e.RemotePath)
+}
+
+// Importer brings media copied off headsets by hand into the sync dir and
+// the manifest.
+type Importer struct {
+	ADB      *adb.Client // optional; used to match files still on Devices
+	Manifest *manifest.DB
+	Config   *config.Config
+	Mode     ImportMode
+	// Devices are searched for the imported files. A match is recorded
+	// under the headset's own path, so 'clean' can delete it from the
+	// headset once it has been pushed.
+	Devices []adb.Device
+	// Device is the key recorded for files that matched no headset.
+	// Defaults to ImportDevice.
+	Device string
+	DryRun bool
+}
+
+// ImportResult summarizes an import.
+type ImportResult struct {
+	Imported int // added to the manifest
+	Matched  int // of those, found on a headset
+	Skipped  int // already in the manifest
+	Ignored  int // not media
+	Errors   []string
+}
+
+type deviceFile struct {
+	device adb.Device
+	file   adb.FileInfo
+}
+
+// Import walks dir and imports every media file in it. Files are keyed by
+// hash: anything whose SHA-256 the manifest already has is skipped. Files
+// pulled before hashes were recorded are hashed from their local copies
+// when one has the same name and size as a file being imported.
+func (im *Importer) Import(dir string) (ImportResult, error) {
+	var result ImportResult
+	syncDir := im.Config.ExpandSyncDir()
+	fallbackDevice := im.Device
+	if fallbackDevice == "" {
+		fallbackDevice = ImportDevice
+	}
+
+	type nameSize struct {
+		name string
+		size int64
+	}
+	all, err := im.Manifest.AllFiles()
+	if err != nil {
+		return result, err
+	}
+	unhashed := make(map[nameSize][]manifest.Entry)
+	for _, e := range all {
+		if e.SHA256 == "" && e.LocalPath != "" {
+			k := nameSize{path.Base(e.RemotePath), e.Size}
+			unhashed[k] = append(unhashed[k], e)
+		}
+	}
+
+	onDevice := make(map[nameSize][]deviceFile)
+	for _, d := range im.Devices {
+		for _, mediaPath := range im.Config.MediaPaths {
+			files, err := im.ADB.ListFilesRecursive(d.Serial, mediaPath)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("list %s on %s: %v", mediaPath, d.ID, err))
+				continue
+			}
+			for _, f := range files {
+				k := nameSize{path.Base(f.Path), f.Size}
+				onDevice[k] = append(onDevice[k], deviceFile{d, f})
+			}
+		}
+	}
+
+	err = filepath.WalkDir(dir, func(src string, de fs.DirEntry, err error) error {
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return nil
+		}
+		if de.IsDir() {
+			if src != dir && strings.HasPrefix(de.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		_, ok := importExts[strings.ToLower(filepath.Ext(src))]
+		if !ok || !de.Type().IsRegular() || strings.HasSuffix(src, PartialSuffix) {
+			result.Ignored++
+			return nil
+		}
+		info, err := de.Info()
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return nil
+		}
+
+		sum, err := HashFile(src)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("hash %s: %v", src, err))
+			return nil
+		}
+		if known, err := im.Manifest.HasHash(sum); err != nil {
+			return err
+		} else if known {
+			result.Skipped++
+			return nil
+		}
+		k := nameSize{filepath.Base(src), info.Size()}
+		known, err := im.hashOlder(unhashed[k], sum)
+		if !im.DryRun {
+			delete(unhashed, k) // hashed now, or can't be
+		}
+		if err != nil {
+			return err
+		}
+		if known {
+			result.Skipped++
+			return nil
+		}
+
+		// Same name, size and hash on a headset: record it as that file.
+		deviceID := fallbackDevice
+		remotePath := manifest.ImportScheme + sum + "/" + filepath.Base(src)
+		mtime := info.ModTime()
+		matched := false
+		for _, c := range onDevice[nameSize{filepath.Base(src), info.Size()}] {
+			deviceSum, err := im.ADB.SHA256(context.Background(), c.device.Serial, c.file.Path)
+			if err != nil || deviceSum != sum {
+				continue
+			}
+			deviceID, remotePath, mtime = c.device.ID, c.file.Path, c.file.MTime
+			matched = true
+			break
+		}
+		if matched {
+			if pulled, _ := im.Manifest.IsPulled(deviceID, remotePath, info.Size(), mtime.Unix()); pulled {
+				result.Skipped++
+				return nil
+			}
+		}
+
+		md := readMedia(src, remotePath)
+		entry, err := withApp(im.Manifest, manifest.Entry{DeviceSerial: deviceID, RemotePath: remotePath,
+			Size: info.Size(), MTime: mtime.Unix(), SHA256: sum, Media: md})
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(syncDir, filepath.FromSlash(RelPath(im.Config, entry)))
+		localPath, err := freePath(im.Manifest, target, entry, nil)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			return nil
+		}
+		if matched {
+			fmt.Printf("  %s -> %s (on %s as %s)\n", src, localPath, deviceID, remotePath)
+		} else {
+			fmt.Printf("  %s -> %s\n", src, localPath)
+		}
+		if im.DryRun {
+			result.Imported++
+			if matched {
+				result.Matched++
+			}
+			return nil
+		}
+
+		if err := placeFile(src, localPath, im.Mode); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("import %s: %v", src, err))
+			return nil
+		}
+		if err := os.Chtimes(localPath, mtime, mtime); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("chtimes %s: %v", localPath, err))
+		}
+		fileID, err := im.Manifest.RecordPull(deviceID, remotePath, localPath, info.Size(), mtime.Unix(), sum)
+		if err != nil {
+			return err
+		}
+		if !md.IsZero() {
+			if err := im.Manifest.RecordMedia(fileID, md); err != nil {
+				return err
+			}
+		}
+		result.Imported++
+		if matched {
+			result.Matched++
+		}
+		return nil
+	})
+	return result, err
+}
+
+// hashOlder hashes the local copies of files pulled before hashes were
+// recorded, records the hashes, and reports whether one matches sum.
+func (im *Importer) hashOlder(files []manifest.Entry, sum string) (bool, error) {
+	found := false
+	for _, e := range files {
+		got, err := HashFile(e.LocalPath)
+		if err != nil {
+			continue // no local copy left to hash
+		}
+		if !im.DryRun {
+			if err := im.Manifest.RecordHash(e.ID, got); err != nil {
+				return found, err
+			}
+		}
+		found = found || got == sum
+	}
+	return found, nil
+}
+
+// sameFile reports whether a and b are the same file on disk.
+func sameFile(a, b string) (bool, error) {
+	ai, err := os.Stat(a)
+	if err != nil {
+		return false, err
+	}
+	bi, err := os.Stat(b)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	return os.SameFile(ai, bi), nil
+}
+
+// placeFile puts src at dst using mode. Nothing happens if dst already
+// holds the file (see freePath).
+func placeFile(src, dst string, mode ImportMode) error {
+	if _, err := os.Stat(dst); err == nil {
+		if mode == ImportMove {
+			if same, _ := sameFile(src, dst); !same {
+				return os.Remove(src)
+			}
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	switch mode {
+	case ImportLink:
+		return os.Link(src, dst)
+	case ImportMove:
+		if err := os.Rename(src, dst); err == nil {
+			return nil
+		}
+		// Different filesystem: copy, then remove the original.
+		if err := copyLocal(src, dst); err != nil {
+			return err
+		}
+		return os.Remove(src)
+	default:
+		return copyLocal(src, dst)
+	}
+}
+
+func copyLocal(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	tmp := dst + PartialSuffix
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
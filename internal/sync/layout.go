@@ -0,0 +1,231 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+)
+
+// Where files go is set by path templates (config.DefaultLayout unless
+// the config says otherwise): the layout under sync_dir, and optionally a
+// different one per destination. Destinations without their own layout
+// mirror the local path. The default keeps each headset in its own folder
+// so two headsets that produce a file with the same name don't overwrite
+// each other.
+
+var unsafeFolderChars = regexp.MustCompile(`[<>:"/\\|?*\x00-\x1f]+`)
+
+// DeviceFolder returns the folder a device's files are kept in.
+func DeviceFolder(cfg *config.Config, deviceID string) string {
+	name := strings.TrimSpace(cfg.Devices[deviceID].Nickname)
+	if name == "" {
+		name = deviceID
+	}
+	return segment(name)
+}
+
+// segment makes s safe to use as one path segment.
+func segment(s string) string {
+	s = strings.Trim(unsafeFolderChars.ReplaceAllString(s, "_"), ". ")
+	if s == "" {
+		return "Unknown"
+	}
+	return s
+}
+
+// RenderLayout returns where template puts e, slash-separated and relative
+// to the sync dir or destination root.
+func RenderLayout(cfg *config.Config, template string, e manifest.Entry) string {
+	t := e.CaptureTime()
+	name := path.Base(e.RemotePath)
+	p := config.ExpandLayout(template, map[string]string{
+		"device_nickname": DeviceFolder(cfg, e.DeviceSerial),
+		"device_serial":   segment(e.DeviceSerial),
+		"year":            t.Format("2006"),
+		"month":           t.Format("01"),
+		"day":             t.Format("02"),
+		"type":            MediaType(e),
+		"app":             segment(AppName(e)),
+		"app_package":     segment(AppPackage(e)),
+		"name":            segment(name),
+	})
+	var parts []string
+	for _, seg := range strings.Split(p, "/") {
+		if seg = strings.TrimSpace(seg); seg != "" && seg != "." && seg != ".." {
+			parts = append(parts, seg)
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+// RelPath returns where the local layout puts a file, relative to the sync
+// dir, with forward slashes.
+func RelPath(cfg *config.Config, e manifest.Entry) string {
+	return RenderLayout(cfg, cfg.LocalLayout(), e)
+}
+
+// DestObjectPath returns where e is uploaded on dest: its own layout if it
+// has one, otherwise the same path as the local copy under baseDir (see
+// ObjectPath).
+func DestObjectPath(cfg *config.Config, dest config.Destination, baseDir string, e manifest.Entry) string {
+	if dest.Layout == "" {
+		return ObjectPath(baseDir, e)
+	}
+	return RenderLayout(cfg, dest.Layout, e)
+}
+
+// NewObjectPath is DestObjectPath for a file about to be uploaded. If the
+// manifest already has a different file at that path on dest, a numbered
+// name is used instead.
+func NewObjectPath(db *manifest.DB, cfg *config.Config, dest config.Destination, baseDir string, e manifest.Entry) (string, error) {
+	e, err := withApp(db, e)
+	if err != nil {
+		return "", err
+	}
+	p := DestObjectPath(cfg, dest, baseDir, e)
+	if dest.Layout == "" {
+		return p, nil // the local path is already unique
+	}
+	return freeObjectPath(db, dest, p, e, nil)
+}
+
+// freeObjectPath returns p, or the first numbered variant of it, that no
+// other file in the manifest (or in reserved) uses on dest.
+func freeObjectPath(db *manifest.DB, dest config.Destination, p string, e manifest.Entry, reserved map[string]bool) (string, error) {
+	dir, name := path.Split(p)
+	for i := 0; ; i++ {
+		candidate := dir + numberedName(name, i)
+		if reserved[candidate] {
+			continue
+		}
+		owner, err := db.ObjectOwner(dest.Name, candidate)
+		if err != nil {
+			return "", err
+		}
+		if owner == 0 || owner == e.ID {
+			return candidate, nil
+		}
+	}
+}
+
+// errNeedHash means an untracked file of the right size is already where
+// e belongs, and telling whether it is the same file takes e's hash.
+var errNeedHash = errors.New("file hash needed")
+
+// LocalPathFor returns where a new file from a headset goes under baseDir.
+// If a different file is already there, a numbered name is used instead
+// (name-1.mp4, name-2.mp4, ...) so neither is overwritten. e.SHA256 must
+// be set for files not yet pulled; see PullPathFor.
+func LocalPathFor(db *manifest.DB, cfg *config.Config, baseDir string, e manifest.Entry) (string, error) {
+	e, err := withApp(db, e)
+	if err != nil {
+		return "", err
+	}
+	return freePath(db, filepath.Join(baseDir, filepath.FromSlash(RelPath(cfg, e))), e, nil)
+}
+
+// PullPathFor is LocalPathFor for a file about to be pulled from the
+// headset with transport serial. Its hash is only taken on the headset if
+// an untracked file that may be the same one is in the way.
+func PullPathFor(ctx context.Context, a *adb.Client, serial string, db *manifest.DB, cfg *config.Config, baseDir string, e manifest.Entry) (string, error) {
+	p, err := LocalPathFor(db, cfg, baseDir, e)
+	if !errors.Is(err, errNeedHash) {
+		return p, err
+	}
+	if e.SHA256, err = a.SHA256(ctx, serial, e.RemotePath); err != nil {
+		return "", err
+	}
+	return LocalPathFor(db, cfg, baseDir, e)
+}
+
+// freePath returns target, or the first numbered variant of it, that isn't
+// taken by another file. A path is taken if it is in reserved, if the
+// manifest records it for a different headset file, or if an untracked
+// file with different content is there. An untracked file with the same
+// content is this one from an earlier pull, and may be replaced. The
+// content is compared by e.SHA256, or the hash of e.LocalPath; without
+// either, freePath returns errNeedHash.
+func freePath(db *manifest.DB, target string, e manifest.Entry, reserved map[string]bool) (string, error) {
+	dir := filepath.Dir(target)
+	name := filepath.Base(target)
+	for i := 0; ; i++ {
+		candidate := filepath.Join(dir, numberedName(name, i))
+		if reserved[candidate] {
+			continue
+		}
+		owners, err := db.QueryByLocalPath(candidate)
+		if err != nil {
+			return "", err
+		}
+		taken, ours := false, false
+		for _, o := range owners {
+			if o.DeviceSerial == e.DeviceSerial && o.RemotePath == e.RemotePath {
+				ours = true
+			} else {
+				taken = true
+			}
+		}
+		if taken {
+			continue
+		}
+		if !ours {
+			fi, err := os.Stat(candidate)
+			if err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return "", err
+			}
+			if err == nil {
+				same, err := sameContent(candidate, fi, e)
+				if err != nil {
+					return "", err
+				}
+				if !same {
+					continue
+				}
+			}
+		}
+		return candidate, nil
+	}
+}
+
+// sameContent reports whether the file at p, described by fi, is the file
+// e describes.
+func sameContent(p string, fi fs.FileInfo, e manifest.Entry) (bool, error) {
+	if fi.Size() != e.Size {
+		return false, nil
+	}
+	sum := e.SHA256
+	if sum == "" && e.LocalPath != "" {
+		var err error
+		if sum, err = HashFile(e.LocalPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return false, err
+		}
+	}
+	if sum == "" {
+		return false, errNeedHash
+	}
+	got, err := HashFile(p)
+	if err != nil {
+		return false, err
+	}
+	return got == sum, nil
+}
+
+// numberedName returns name for i == 0, and name-<i> (before the
+// extension) otherwise.
+func numberedName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext)
+}
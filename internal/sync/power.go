@@ -0,0 +1,49 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+)
+
+// ErrLowBattery is returned when a headset is below the configured charge
+// level, not charging, and the user didn't choose to continue.
+var ErrLowBattery = errors.New("battery too low")
+
+// PowerGuard checks a headset's battery before it is synced and keeps it
+// awake during the transfer.
+type PowerGuard struct {
+	ADB *adb.Client
+	// MinBattery is the lowest charge (percent) a headset that isn't
+	// charging may sync at. 0 disables the check.
+	MinBattery int
+	// Confirm is asked whether to go ahead when the battery is low.
+	// Nil refuses.
+	Confirm func(d adb.Device, b adb.Battery) bool
+}
+
+// Acquire runs the battery check and keeps the device awake. The returned
+// release function must be called when the transfer is done. Failing to
+// read the battery or change the sleep setting is not fatal: some builds
+// don't allow it, and the sync is still worth trying.
+func (g *PowerGuard) Acquire(d adb.Device) (release func(), err error) {
+	if g.MinBattery > 0 {
+		if b, err := g.ADB.Battery(d.Serial); err == nil && !b.Charging && b.Level < g.MinBattery {
+			if g.Confirm == nil || !g.Confirm(d, b) {
+				return nil, fmt.Errorf("%w: %d%% and not charging (minimum %d%%)", ErrLowBattery, b.Level, g.MinBattery)
+			}
+		}
+	}
+	restore, err := g.ADB.KeepAwake(d.Serial)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not keep %s awake: %v\n", d.ID, err)
+		return func() {}, nil
+	}
+	return func() {
+		if err := restore(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}, nil
+}
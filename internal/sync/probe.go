@@ -0,0 +1,109 @@
+package sync
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/media"
+)
+
+// ProbeMedia reads the capture metadata of the file at path. Files that
+// can't be read as media get none.
+func ProbeMedia(path string) manifest.Media {
+	info, err := media.Probe(path)
+	if err != nil {
+		return manifest.Media{}
+	}
+	md := manifest.Media{
+		DurationMS: info.Duration.Milliseconds(),
+		Width:      info.Width,
+		Height:     info.Height,
+		Codec:      info.Codec,
+	}
+	if !info.CapturedAt.IsZero() {
+		md.CapturedAt = info.CapturedAt.Unix()
+	}
+	return md
+}
+
+// readMedia is ProbeMedia, with the capture time taken from the Quest file
+// name at remotePath if the file itself doesn't record one, as screenshots
+// don't.
+func readMedia(localPath, remotePath string) manifest.Media {
+	md := ProbeMedia(localPath)
+	if md.CapturedAt == 0 {
+		if _, at, ok := media.ParseName(path.Base(remotePath)); ok && !at.IsZero() {
+			md.CapturedAt = at.Unix()
+		}
+	}
+	return md
+}
+
+// Settle probes a file just pulled to localPath for e. Its location was
+// picked from the headset's modification time; if the capture time puts it
+// in a different folder under baseDir, it is moved there. It returns where
+// the file ended up and its metadata.
+func Settle(db *manifest.DB, cfg *config.Config, baseDir string, e manifest.Entry, localPath string) (string, manifest.Media, error) {
+	e.Media = readMedia(localPath, e.RemotePath)
+	if e.CapturedAt == 0 {
+		return localPath, e.Media, nil
+	}
+	want, err := LocalPathFor(db, cfg, baseDir, e)
+	if err != nil {
+		return localPath, e.Media, err
+	}
+	if filepath.Dir(want) == filepath.Dir(localPath) {
+		return localPath, e.Media, nil
+	}
+	if err := moveLocal(localPath, want); err != nil {
+		return localPath, e.Media, err
+	}
+	return want, e.Media, nil
+}
+
+// MediaBackfill summarizes BackfillMedia.
+type MediaBackfill struct {
+	Probed  int // metadata read and recorded
+	Empty   int // nothing could be read from the file
+	NoLocal int // no local copy to read
+	Errors  []string
+}
+
+// BackfillMedia reads capture metadata for files pulled before it was
+// recorded, from their local copies. Files already read have dimensions;
+// with all, they are read again.
+func BackfillMedia(db *manifest.DB, all bool) (MediaBackfill, error) {
+	var result MediaBackfill
+	files, err := db.AllFiles()
+	if err != nil {
+		return result, err
+	}
+	for _, e := range files {
+		if !all && e.Width != 0 {
+			continue
+		}
+		if e.LocalPath == "" {
+			result.NoLocal++
+			continue
+		}
+		if _, err := os.Stat(e.LocalPath); err != nil {
+			result.NoLocal++
+			continue
+		}
+		md := readMedia(e.LocalPath, e.RemotePath)
+		if md.IsZero() {
+			result.Empty++
+			continue
+		}
+		if err := db.RecordMedia(e.ID, md); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", e.LocalPath, err))
+			continue
+		}
+		result.Probed++
+	}
+	return result, nil
+}
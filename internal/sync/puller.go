@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -16,6 +17,8 @@ type Puller struct {
 	ADB      *adb.Client
 	Manifest *manifest.DB
 	Config   *config.Config
+	Power    *PowerGuard // optional battery check and stay-awake
+	Apps     AppFilter   // only pull captures from these apps; nil for all
 }
 
 // PullResult summarizes a pull operation.
@@ -33,14 +36,14 @@ func (p *Puller) PullAll() ([]PullResult, error) {
 		return nil, err
 	}
 	var results []PullResult
-	for _, d := range devices {
+	for _, d := range adb.UniqueDevices(devices) {
 		if !d.IsOnline() {
 			continue
 		}
-		r, err := p.PullDevice(d.Serial)
+		r, err := p.PullDevice(d)
 		if err != nil {
 			results = append(results, PullResult{
-				DeviceSerial: d.Serial,
+				DeviceSerial: d.ID,
 				Errors:       []string{err.Error()},
 			})
 			continue
@@ -51,19 +54,33 @@ func (p *Puller) PullAll() ([]PullResult, error) {
 }
 
 // PullDevice pulls media from a specific device.
-func (p *Puller) PullDevice(serial string) (PullResult, error) {
-	result := PullResult{DeviceSerial: serial}
+func (p *Puller) PullDevice(d adb.Device) (PullResult, error) {
+	result := PullResult{DeviceSerial: d.ID}
 	syncDir := p.Config.ExpandSyncDir()
 
+	if p.Power != nil {
+		release, err := p.Power.Acquire(d)
+		if err != nil {
+			return result, err
+		}
+		defer release()
+	}
+
 	for _, mediaPath := range p.Config.MediaPaths {
-		files, err := p.ADB.ListFilesRecursive(serial, mediaPath)
+		files, err := p.ADB.ListFilesRecursive(d.Serial, mediaPath)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("list %s: %v", mediaPath, err))
 			continue
 		}
+		if err := labelNewApps(p.ADB, p.Manifest, d, files); err != nil {
+			fmt.Printf("  Could not look up app names: %v\n", err)
+		}
 
 		for _, f := range files {
-			pulled, err := p.Manifest.IsPulled(serial, f.Path, f.Size, f.MTime.Unix())
+			if !p.Apps.Match(manifest.Entry{RemotePath: f.Path}) {
+				continue
+			}
+			pulled, err := p.Manifest.IsPulled(d.ID, f.Path, f.Size, f.MTime.Unix())
 			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("check %s: %v", f.Path, err))
 				continue
@@ -73,17 +90,21 @@ func (p *Puller) PullDevice(serial string) (PullResult, error) {
 				continue
 			}
 
-			// Determine local path: sync_dir/MediaType/filename
-			mediaType := mediaTypeFromPath(mediaPath)
-			localDir := filepath.Join(syncDir, mediaType)
-			if err := os.MkdirAll(localDir, 0o755); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("mkdir %s: %v", localDir, err))
+			// Determine local path from the layout
+			localPath, err := PullPathFor(context.Background(), p.ADB, d.Serial, p.Manifest, p.Config, syncDir,
+				manifest.Entry{DeviceSerial: d.ID, RemotePath: f.Path, Size: f.Size, MTime: f.MTime.Unix()})
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("place %s: %v", f.Path, err))
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("mkdir %s: %v", filepath.Dir(localPath), err))
 				continue
 			}
-			localPath := filepath.Join(localDir, filepath.Base(f.Path))
 
 			fmt.Printf("  Pulling %s -> %s\n", f.Path, localPath)
-			if err := p.ADB.Pull(serial, f.Path, localPath); err != nil {
+			sum, err := FetchFile(context.Background(), p.ADB, d.Serial, f, localPath, nil)
+			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("pull %s: %v", f.Path, err))
 				continue
 			}
@@ -93,13 +114,31 @@ func (p *Puller) PullDevice(serial string) (PullResult, error) {
 				result.Errors = append(result.Errors, fmt.Sprintf("chtimes %s: %v", localPath, err))
 			}
 
-			if _, err := p.Manifest.RecordPull(serial, f.Path, localPath, f.Size, f.MTime.Unix()); err != nil {
+			localPath, md, err := Settle(p.Manifest, p.Config, syncDir,
+				manifest.Entry{DeviceSerial: d.ID, RemotePath: f.Path, Size: f.Size, MTime: f.MTime.Unix(), SHA256: sum}, localPath)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("place %s: %v", f.Path, err))
+			}
+
+			fileID, err := p.Manifest.RecordPull(d.ID, f.Path, localPath, f.Size, f.MTime.Unix(), sum)
+			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("record %s: %v", f.Path, err))
 				continue
 			}
+			if !md.IsZero() {
+				if err := p.Manifest.RecordMedia(fileID, md); err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("record %s: %v", f.Path, err))
+				}
+			}
 			result.FilesPulled++
 		}
 	}
+
+	// Anything still partial by now is from a pull that was never resumed.
+	// The layout may put files anywhere in the sync dir, so look everywhere.
+	if n := CleanPartials(syncDir); n > 0 {
+		fmt.Printf("  Removed %d stale partial file(s) from %s\n", n, syncDir)
+	}
 	return result, nil
 }
 
@@ -1,9 +1,13 @@
 package sync
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/FluidXR/fetchquest/internal/config"
 	"github.com/FluidXR/fetchquest/internal/manifest"
@@ -15,6 +19,7 @@ type Pusher struct {
 	Rclone   *rclone.Client
 	Manifest *manifest.DB
 	Config   *config.Config
+	Apps     AppFilter // only push captures from these apps; nil for all
 }
 
 // PushResult summarizes a push operation.
@@ -32,6 +37,7 @@ func (p *Pusher) PushAll() ([]PushResult, error) {
 		fmt.Printf("Checking %s... ", dest.Name)
 		if !p.Rclone.IsReachable(dest.RcloneRemote) {
 			fmt.Printf("unreachable, skipping\n")
+			_ = p.Manifest.RecordDestProbe(dest.Name, false, "destination unreachable", time.Time{})
 			results = append(results, PushResult{
 				Destination: dest.Name,
 				Errors:      []string{"destination unreachable"},
@@ -39,6 +45,7 @@ func (p *Pusher) PushAll() ([]PushResult, error) {
 			continue
 		}
 		fmt.Printf("ok\n")
+		_ = p.Manifest.RecordDestProbe(dest.Name, true, "", time.Time{})
 		r, err := p.PushToDest(dest)
 		if err != nil {
 			results = append(results, PushResult{
@@ -47,6 +54,9 @@ func (p *Pusher) PushAll() ([]PushResult, error) {
 			})
 			continue
 		}
+		if r.FilesPushed > 0 {
+			_ = p.Manifest.RecordDestPushed(dest.Name)
+		}
 		results = append(results, r)
 	}
 	return results, nil
@@ -55,37 +65,71 @@ func (p *Pusher) PushAll() ([]PushResult, error) {
 // PushToDest uploads unpushed files to a specific destination.
 func (p *Pusher) PushToDest(dest config.Destination) (PushResult, error) {
 	result := PushResult{Destination: dest.Name}
+	syncDir := p.Config.ExpandSyncDir()
+
+	// Uploads recorded before they were verified: check them now, and
+	// queue anything missing or damaged for another upload.
+	unverified, err := p.Manifest.GetUnverifiedFiles(dest.Name)
+	if err != nil {
+		return result, err
+	}
+	for _, entry := range unverified {
+		ds, err := VerifyUpload(p.Rclone, dest, SyncedObjectPath(syncDir, entry), entry.Size, entry.SHA256)
+		if err == nil {
+			ds.FileID = entry.ID
+			err = p.Manifest.MarkVerified(ds)
+		} else if errors.Is(err, rclone.ErrNotFound) || errors.Is(err, rclone.ErrMismatch) {
+			fmt.Printf("  %v — uploading again\n", err)
+			err = p.Manifest.DeleteDestSync(entry.ID, dest.Name)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
 
 	entries, err := p.Manifest.GetUnpushedFiles(dest.Name)
 	if err != nil {
 		return result, err
 	}
 
-	syncDir := p.Config.ExpandSyncDir()
 	for _, entry := range entries {
+		if !p.Apps.Match(entry) {
+			continue
+		}
 		if entry.LocalPath == "" {
 			result.FilesSkipped++
 			continue
 		}
-
-		// Build rclone dest path: remote:path/serial/MediaType/filename
-		relPath, err := filepath.Rel(syncDir, entry.LocalPath)
+		objectPath, err := NewObjectPath(p.Manifest, p.Config, dest, syncDir, entry)
 		if err != nil {
-			relPath = filepath.Base(entry.LocalPath)
+			result.Errors = append(result.Errors, fmt.Sprintf("place %s: %v", entry.LocalPath, err))
+			continue
 		}
-		remoteDest := dest.RcloneRemote
-		if !strings.HasSuffix(remoteDest, "/") {
-			remoteDest += "/"
+		remoteDest := dest.Remote(objectPath)
+
+		sum := entry.SHA256
+		if sum == "" {
+			// Pulled before hashes were recorded: at least check the upload
+			// matches the local copy.
+			if sum, err = HashFile(entry.LocalPath); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("hash %s: %v", entry.LocalPath, err))
+				continue
+			}
 		}
-		remoteDest += filepath.ToSlash(relPath)
 
 		fmt.Printf("  Uploading %s -> %s\n", entry.LocalPath, remoteDest)
 		if err := p.Rclone.Copy(entry.LocalPath, remoteDest); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("push %s: %v", entry.LocalPath, err))
 			continue
 		}
+		ds, err := VerifyUpload(p.Rclone, dest, objectPath, entry.Size, sum)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
 
-		if err := p.Manifest.RecordDestSync(entry.ID, dest.Name); err != nil {
+		ds.FileID = entry.ID
+		if err := p.Manifest.RecordDestSync(ds); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("record sync %s: %v", entry.LocalPath, err))
 			continue
 		}
@@ -94,9 +138,16 @@ func (p *Pusher) PushToDest(dest config.Destination) (PushResult, error) {
 	return result, nil
 }
 
-// PushFile uploads a single file to all destinations and records it.
-// If baseDir is empty, the config's sync dir is used to compute relative paths.
-func (p *Pusher) PushFile(fileID int64, localPath, baseDir string) ([]PushResult, error) {
+// PushFile uploads a single file, entry.LocalPath, to all destinations and
+// records it. entry.SHA256 is the file's verified hash, checked against
+// each destination's copy. If baseDir is empty, the config's sync dir is
+// used to compute relative paths.
+func (p *Pusher) PushFile(entry manifest.Entry, baseDir string) ([]PushResult, error) {
+	localPath, sha256 := entry.LocalPath, entry.SHA256
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return nil, fmt.Errorf("stat %s: %w", localPath, err)
+	}
 	var results []PushResult
 	syncDir := baseDir
 	if syncDir == "" {
@@ -105,16 +156,13 @@ func (p *Pusher) PushFile(fileID int64, localPath, baseDir string) ([]PushResult
 
 	for _, dest := range p.Config.Destinations {
 		result := PushResult{Destination: dest.Name}
-
-		relPath, err := filepath.Rel(syncDir, localPath)
+		objectPath, err := NewObjectPath(p.Manifest, p.Config, dest, syncDir, entry)
 		if err != nil {
-			relPath = filepath.Base(localPath)
-		}
-		remoteDest := dest.RcloneRemote
-		if !strings.HasSuffix(remoteDest, "/") {
-			remoteDest += "/"
+			result.Errors = append(result.Errors, fmt.Sprintf("place %s: %v", localPath, err))
+			results = append(results, result)
+			continue
 		}
-		remoteDest += filepath.ToSlash(relPath)
+		remoteDest := dest.Remote(objectPath)
 
 		fmt.Printf("  Uploading %s -> %s\n", localPath, remoteDest)
 		if err := p.Rclone.Copy(localPath, remoteDest); err != nil {
@@ -122,8 +170,15 @@ func (p *Pusher) PushFile(fileID int64, localPath, baseDir string) ([]PushResult
 			results = append(results, result)
 			continue
 		}
+		ds, err := VerifyUpload(p.Rclone, dest, objectPath, info.Size(), sha256)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			results = append(results, result)
+			continue
+		}
 
-		if err := p.Manifest.RecordDestSync(fileID, dest.Name); err != nil {
+		ds.FileID = entry.ID
+		if err := p.Manifest.RecordDestSync(ds); err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("record sync %s: %v", localPath, err))
 		}
 		result.FilesPushed = 1
@@ -131,3 +186,44 @@ func (p *Pusher) PushFile(fileID int64, localPath, baseDir string) ([]PushResult
 	}
 	return results, nil
 }
+
+// VerifyUpload checks the object at objectPath on dest after an upload: it
+// must exist with the expected size and, where the backend reports SHA-256,
+// the expected hash. It returns the sync record to store for it (without
+// FileID), describing what the destination actually holds.
+func VerifyUpload(rc *rclone.Client, dest config.Destination, objectPath string, size int64, sha256 string) (manifest.DestSync, error) {
+	obj, err := rc.Check(dest.Remote(objectPath), size, sha256)
+	if err != nil {
+		return manifest.DestSync{}, fmt.Errorf("verify upload: %w", err)
+	}
+	return manifest.DestSync{
+		Destination: dest.Name,
+		ObjectPath:  objectPath,
+		Size:        obj.Size,
+		SHA256:      obj.SHA256,
+		ObjectID:    obj.ID,
+	}, nil
+}
+
+// ObjectPath returns where a file is uploaded under a destination's root:
+// its path relative to the sync dir, normally MediaType/filename. Files
+// that were streamed without a local copy, or whose local copy is outside
+// the sync dir, are placed by media type.
+func ObjectPath(syncDir string, entry manifest.Entry) string {
+	if entry.LocalPath != "" {
+		if relPath, err := filepath.Rel(syncDir, entry.LocalPath); err == nil && !strings.HasPrefix(relPath, "..") {
+			return filepath.ToSlash(relPath)
+		}
+		return filepath.Base(filepath.Dir(entry.LocalPath)) + "/" + filepath.Base(entry.LocalPath)
+	}
+	return mediaTypeFromPath(path.Dir(entry.RemotePath)) + "/" + path.Base(entry.RemotePath)
+}
+
+// SyncedObjectPath returns the object path recorded for a sync, falling
+// back to ObjectPath for syncs recorded before paths were stored.
+func SyncedObjectPath(syncDir string, e manifest.SyncedEntry) string {
+	if e.Sync.ObjectPath != "" {
+		return e.Sync.ObjectPath
+	}
+	return ObjectPath(syncDir, e.Entry)
+}
@@ -0,0 +1,229 @@
+package sync
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+)
+
+// mtimeSlack is how far a destination's modification time may be from the
+// headset's and still count as a match. Backends store mtimes at
+// different precisions (FAT-style storage only to 2 seconds).
+const mtimeSlack = 2 * time.Second
+
+// RebuildMatch pairs a file on a headset with a copy already on a destination.
+type RebuildMatch struct {
+	DeviceID  string
+	File      adb.FileInfo
+	Object    rclone.Object
+	ByMTime   bool   // the modification time matched too, not just name and size
+	SHA256    string // hash taken on the headset, when hashes were compared
+	LocalPath string // existing copy in the sync dir, "" if none
+}
+
+// RebuildPlan is what RebuildFrom would record for one destination.
+type RebuildPlan struct {
+	Destination config.Destination
+	Matches     []RebuildMatch
+	Ambiguous   []adb.FileInfo // several copies fit and none is a clear match
+	Mismatched  []adb.FileInfo // name and size matched, but the hash didn't
+	Unconfirmed []RebuildMatch // only name and size matched; not recorded
+	Unmatched   int            // headset files with no copy on the destination
+	Known       int            // already recorded as synced to the destination
+	Errors      []string
+}
+
+// rebuildFile is a headset file with the destination objects it could be.
+type rebuildFile struct {
+	device     adb.Device
+	file       adb.FileInfo
+	candidates []rclone.Object
+}
+
+// PlanRebuild walks dest and matches its objects to the media on devices
+// by file name and size, using the modification time the upload
+// preserved to pick between copies with the same name. With hashes, each
+// match is also confirmed by SHA-256 where the backend stores one. Only
+// matches confirmed by modification time or hash are recorded: another
+// headset may well have a file with the same name and size. Each object is
+// matched to one file at most, preferring copies in the headset's own
+// folder. Nothing is recorded; see RebuildPlan.Apply.
+func PlanRebuild(adbClient *adb.Client, rc *rclone.Client, db *manifest.DB, cfg *config.Config,
+	dest config.Destination, devices []adb.Device, hashes bool) (RebuildPlan, error) {
+	plan := RebuildPlan{Destination: dest}
+	objs, err := rc.List(dest.RcloneRemote, hashes)
+	if err != nil {
+		return plan, err
+	}
+	type nameSize struct {
+		name string
+		size int64
+	}
+	byName := make(map[nameSize][]rclone.Object)
+	claimed := make(map[string]bool) // object paths already matched to a file
+	for _, o := range objs {
+		if strings.HasPrefix(o.Path, ".fetchquest/") {
+			continue
+		}
+		if owner, err := db.ObjectOwner(dest.Name, o.Path); err != nil {
+			return plan, err
+		} else if owner != 0 {
+			claimed[o.Path] = true
+		}
+		k := nameSize{path.Base(o.Path), o.Size}
+		byName[k] = append(byName[k], o)
+	}
+
+	var timed, untimed []rebuildFile
+	for _, d := range devices {
+		for _, mediaPath := range cfg.MediaPaths {
+			files, err := adbClient.ListFilesRecursive(d.Serial, mediaPath)
+			if err != nil {
+				plan.Errors = append(plan.Errors, fmt.Sprintf("list %s on %s: %v", mediaPath, d.ID, err))
+				continue
+			}
+			for _, f := range files {
+				candidates := byName[nameSize{path.Base(f.Path), f.Size}]
+				if len(candidates) == 0 {
+					plan.Unmatched++
+					continue
+				}
+				if synced, _ := db.IsFullySynced(d.ID, f.Path, []string{dest.Name}); synced {
+					plan.Known++
+					continue
+				}
+				rf := rebuildFile{d, f, candidates}
+				if len(sameMTime(candidates, f)) > 0 {
+					timed = append(timed, rf)
+				} else {
+					untimed = append(untimed, rf)
+				}
+			}
+		}
+	}
+
+	// Files with a copy whose modification time matches claim theirs first,
+	// so a weaker match can't take it from them.
+	syncDir := cfg.ExpandSyncDir()
+	for _, rf := range append(timed, untimed...) {
+		var avail []rclone.Object
+		for _, o := range rf.candidates {
+			if !claimed[o.Path] {
+				avail = append(avail, o)
+			}
+		}
+		m := RebuildMatch{DeviceID: rf.device.ID, File: rf.file}
+		pool := sameMTime(avail, rf.file)
+		m.ByMTime = len(pool) > 0
+		if !m.ByMTime {
+			pool = avail
+		}
+		if len(pool) > 1 {
+			if own := inDeviceFolder(cfg, rf.device.ID, pool); len(own) > 0 {
+				pool = own
+			}
+		}
+		switch len(pool) {
+		case 0:
+			plan.Unmatched++
+			continue
+		case 1:
+			m.Object = pool[0]
+		default:
+			plan.Ambiguous = append(plan.Ambiguous, rf.file)
+			continue
+		}
+
+		if hashes && m.Object.SHA256 != "" {
+			sum, err := adbClient.SHA256(context.Background(), rf.device.Serial, rf.file.Path)
+			if err != nil {
+				plan.Errors = append(plan.Errors, err.Error())
+				continue
+			}
+			if sum != m.Object.SHA256 {
+				plan.Mismatched = append(plan.Mismatched, rf.file)
+				continue
+			}
+			m.SHA256 = sum
+		}
+		if !m.ByMTime && m.SHA256 == "" {
+			plan.Unconfirmed = append(plan.Unconfirmed, m)
+			continue
+		}
+
+		claimed[m.Object.Path] = true
+		local := filepath.Join(syncDir, filepath.FromSlash(m.Object.Path))
+		if fi, err := os.Stat(local); err == nil && fi.Size() == rf.file.Size {
+			m.LocalPath = local
+		}
+		plan.Matches = append(plan.Matches, m)
+	}
+	return plan, nil
+}
+
+// sameMTime returns the objects whose modification time is within
+// mtimeSlack of f's.
+func sameMTime(objs []rclone.Object, f adb.FileInfo) []rclone.Object {
+	var out []rclone.Object
+	for _, o := range objs {
+		if diff := o.ModTime.Sub(f.MTime); diff < mtimeSlack && diff > -mtimeSlack {
+			out = append(out, o)
+		}
+	}
+	return out
+}
+
+// inDeviceFolder returns the objects with the device's folder, or its
+// serial, somewhere in their path.
+func inDeviceFolder(cfg *config.Config, deviceID string, objs []rclone.Object) []rclone.Object {
+	folder := DeviceFolder(cfg, deviceID)
+	var out []rclone.Object
+	for _, o := range objs {
+		for _, seg := range strings.Split(path.Dir(o.Path), "/") {
+			if seg == folder || seg == deviceID {
+				out = append(out, o)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Apply recreates the files and dest_syncs rows for every match, as
+// verified. It returns the number recorded.
+func (p RebuildPlan) Apply(db *manifest.DB) (int, error) {
+	n := 0
+	now := time.Now()
+	for _, m := range p.Matches {
+		e := manifest.Entry{
+			DeviceSerial: m.DeviceID,
+			RemotePath:   m.File.Path,
+			LocalPath:    m.LocalPath,
+			Size:         m.File.Size,
+			MTime:        m.File.MTime.Unix(),
+			SHA256:       m.SHA256,
+		}
+		ds := manifest.DestSync{
+			Destination: p.Destination.Name,
+			ObjectPath:  m.Object.Path,
+			Size:        m.Object.Size,
+			SHA256:      m.Object.SHA256,
+			ObjectID:    m.Object.ID,
+			VerifiedAt:  &now,
+		}
+		if err := db.RecordFound(e, ds); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,369 @@
+package sync
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+	"github.com/FluidXR/fetchquest/internal/rclone"
+)
+
+// RemoteMove is a destination copy that has to move.
+type RemoteMove struct {
+	Dest     config.Destination
+	From, To string // object paths
+}
+
+// LayoutMove brings one file in line with the layout. To is empty if the
+// local copy was overwritten by another headset's file and is forgotten.
+type LayoutMove struct {
+	Entry    manifest.Entry
+	From, To string // local paths; equal if the local copy stays put
+	Remote   []RemoteMove
+}
+
+// LayoutPlan is what it takes to move existing files to where the
+// configured layouts put them.
+type LayoutPlan struct {
+	Moves []LayoutMove
+	// Lost files were overwritten, locally and at every destination, by
+	// another headset's file with the same name. They are forgotten so
+	// they're pulled again if they are still on the headset.
+	Lost []manifest.Entry
+	// Requeued uploads were overwritten at a destination by another file
+	// with the same name. They are forgotten so the next push sends them
+	// again.
+	Requeued []manifest.SyncedEntry
+}
+
+// LayoutResult summarizes a relayout.
+type LayoutResult struct {
+	LocalMoved  int
+	RemoteMoved int
+	Errors      []string
+}
+
+// Empty reports whether nothing needs to change.
+func (p LayoutPlan) Empty() bool {
+	return len(p.Moves) == 0 && len(p.Lost) == 0 && len(p.Requeued) == 0
+}
+
+// ForDest narrows the plan to the copies on one destination. Local files
+// stay where they are and no file is forgotten.
+func (p LayoutPlan) ForDest(name string) LayoutPlan {
+	var out LayoutPlan
+	for _, mv := range p.Moves {
+		var remote []RemoteMove
+		for _, r := range mv.Remote {
+			if r.Dest.Name == name {
+				remote = append(remote, r)
+			}
+		}
+		if len(remote) > 0 {
+			out.Moves = append(out.Moves, LayoutMove{Entry: mv.Entry, Remote: remote})
+		}
+	}
+	for _, se := range p.Requeued {
+		if se.Sync.Destination == name {
+			out.Requeued = append(out.Requeued, se)
+		}
+	}
+	return out
+}
+
+// Pending lists the plan's moves in the form they are saved in the
+// manifest.
+func (p LayoutPlan) Pending() []manifest.PendingMove {
+	var moves []manifest.PendingMove
+	for _, mv := range p.Moves {
+		if mv.From != mv.To {
+			moves = append(moves, manifest.PendingMove{FileID: mv.Entry.ID, From: mv.From, To: mv.To})
+		}
+		for _, r := range mv.Remote {
+			moves = append(moves, manifest.PendingMove{FileID: mv.Entry.ID, Destination: r.Dest.Name, From: r.From, To: r.To})
+		}
+	}
+	return moves
+}
+
+// PlanLayout works out where every file in the manifest belongs under the
+// configured layouts and which copies were lost to name collisions in the
+// old one.
+// Copies at destinations that are no longer configured are left alone.
+func PlanLayout(db *manifest.DB, cfg *config.Config) (LayoutPlan, error) {
+	var plan LayoutPlan
+	syncDir := cfg.ExpandSyncDir()
+	dests := make(map[string]config.Destination)
+	for _, d := range cfg.Destinations {
+		dests[d.Name] = d
+	}
+
+	statuses, err := db.FileStatuses()
+	if err != nil {
+		return plan, err
+	}
+
+	// Several entries sharing one local path or object path means all but
+	// one were overwritten. Locally the survivor is the one whose hash
+	// matches the file, or else the latest pull; at a destination it's the
+	// latest upload.
+	byLocal := make(map[string][]manifest.FileStatus)
+	type objKey struct{ dest, path string }
+	byObject := make(map[objKey][]manifest.SyncedEntry)
+	for _, st := range statuses {
+		if st.LocalPath != "" {
+			byLocal[st.LocalPath] = append(byLocal[st.LocalPath], st)
+		}
+		for _, ds := range st.Syncs {
+			se := manifest.SyncedEntry{Entry: st.Entry, Sync: ds}
+			k := objKey{ds.Destination, SyncedObjectPath(syncDir, se)}
+			byObject[k] = append(byObject[k], se)
+		}
+	}
+	localLost := make(map[int64]bool)
+	for localPath, group := range byLocal {
+		if len(group) < 2 {
+			continue
+		}
+		sum, _ := HashFile(localPath)
+		winner := group[0]
+		for _, st := range group[1:] {
+			if survivesLocally(st.Entry, winner.Entry, sum) {
+				winner = st
+			}
+		}
+		for _, st := range group {
+			if st.ID != winner.ID {
+				localLost[st.ID] = true
+			}
+		}
+	}
+	type syncID struct {
+		file int64
+		dest string
+	}
+	remoteLost := make(map[syncID]bool)
+	for _, group := range byObject {
+		if len(group) < 2 {
+			continue
+		}
+		winner := group[0]
+		for _, se := range group[1:] {
+			if se.Sync.SyncedAt.After(winner.Sync.SyncedAt) {
+				winner = se
+			}
+		}
+		for _, se := range group {
+			if se.ID != winner.ID {
+				remoteLost[syncID{se.ID, se.Sync.Destination}] = true
+				plan.Requeued = append(plan.Requeued, se)
+			}
+		}
+	}
+
+	reserved := make(map[string]bool)
+	reservedRemote := make(map[string]map[string]bool)
+	for name := range dests {
+		reservedRemote[name] = make(map[string]bool)
+	}
+	for _, st := range statuses {
+		hasLocal := st.LocalPath != "" && !localLost[st.ID]
+		var syncs []manifest.DestSync
+		for _, ds := range st.Syncs {
+			if !remoteLost[syncID{st.ID, ds.Destination}] {
+				syncs = append(syncs, ds)
+			}
+		}
+		if !hasLocal && len(syncs) == 0 && (localLost[st.ID] || len(st.Syncs) > 0) {
+			plan.Lost = append(plan.Lost, st.Entry)
+			continue
+		}
+
+		want := filepath.Join(syncDir, filepath.FromSlash(RelPath(cfg, st.Entry)))
+		target := ""
+		inSyncDir := hasLocal && isWithin(syncDir, st.LocalPath)
+		if inSyncDir && filepath.Dir(st.LocalPath) == filepath.Dir(want) && !reserved[st.LocalPath] {
+			target = st.LocalPath // already in place
+		} else if target, err = freePath(db, want, st.Entry, reserved); err != nil {
+			return plan, err
+		}
+		reserved[target] = true
+		rel, err := filepath.Rel(syncDir, target)
+		if err != nil {
+			return plan, err
+		}
+		rel = filepath.ToSlash(rel)
+
+		mv := LayoutMove{Entry: st.Entry}
+		if inSyncDir {
+			mv.From, mv.To = st.LocalPath, target
+		} else if localLost[st.ID] {
+			mv.From = st.LocalPath // now another headset's file
+		}
+		for _, ds := range syncs {
+			dest, ok := dests[ds.Destination]
+			if !ok {
+				continue
+			}
+			from := SyncedObjectPath(syncDir, manifest.SyncedEntry{Entry: st.Entry, Sync: ds})
+			to := rel
+			if dest.Layout != "" {
+				if to, err = freeObjectPath(db, dest, RenderLayout(cfg, dest.Layout, st.Entry), st.Entry, reservedRemote[dest.Name]); err != nil {
+					return plan, err
+				}
+				reservedRemote[dest.Name][to] = true
+			}
+			if from != to {
+				mv.Remote = append(mv.Remote, RemoteMove{Dest: dest, From: from, To: to})
+			}
+		}
+		if mv.From != mv.To || len(mv.Remote) > 0 {
+			plan.Moves = append(plan.Moves, mv)
+		}
+	}
+	sort.Slice(plan.Moves, func(i, j int) bool { return plan.Moves[i].Entry.ID < plan.Moves[j].Entry.ID })
+	return plan, nil
+}
+
+// Relayout carries out plan. The moves are saved in the manifest before
+// anything is touched, so an interrupted relayout can be finished with
+// ResumeRelayout even if the layout or the manifest has changed since.
+func Relayout(rc *rclone.Client, db *manifest.DB, cfg *config.Config, plan LayoutPlan) LayoutResult {
+	var result LayoutResult
+	fail := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	for _, se := range plan.Requeued {
+		if err := db.DeleteDestSync(se.ID, se.Sync.Destination); err != nil {
+			fail("%s: %v", se.RemotePath, err)
+		}
+	}
+	for _, e := range plan.Lost {
+		if err := db.DeleteFile(e.ID); err != nil {
+			fail("%s: %v", e.RemotePath, err)
+		}
+	}
+	if err := db.SaveRelayout(plan.Pending()); err != nil {
+		fail("%v", err)
+		return result
+	}
+
+	r := ResumeRelayout(rc, db, cfg)
+	r.Errors = append(result.Errors, r.Errors...)
+	return r
+}
+
+// ResumeRelayout makes the moves still pending in the manifest. Moves that
+// an earlier run already made are recognized and just recorded. A file's
+// new paths are recorded together once its copies have moved; moves that
+// fail stay pending.
+func ResumeRelayout(rc *rclone.Client, db *manifest.DB, cfg *config.Config) LayoutResult {
+	var result LayoutResult
+	fail := func(format string, args ...interface{}) {
+		result.Errors = append(result.Errors, fmt.Sprintf(format, args...))
+	}
+
+	pending, err := db.PendingRelayout()
+	if err != nil {
+		fail("%v", err)
+		return result
+	}
+	dests := make(map[string]config.Destination)
+	for _, d := range cfg.Destinations {
+		dests[d.Name] = d
+	}
+
+	oldDirs := make(map[string]bool)
+	for i := 0; i < len(pending); {
+		j := i
+		for j < len(pending) && pending[j].FileID == pending[i].FileID {
+			j++
+		}
+		var done []manifest.PendingMove
+		local, remote := 0, 0
+		for _, mv := range pending[i:j] {
+			if mv.Local() && mv.To == "" {
+				// Nothing to move: the file there belongs to another entry.
+				fmt.Printf("  Forgetting %s\n", mv.From)
+			} else if mv.Local() {
+				fmt.Printf("  Moving %s -> %s\n", mv.From, mv.To)
+				if err := moveLocal(mv.From, mv.To); err != nil {
+					fail("move %s: %v", mv.From, err)
+					continue
+				}
+				oldDirs[filepath.Dir(mv.From)] = true
+				local++
+			} else {
+				dest, ok := dests[mv.Destination]
+				if !ok {
+					fail("move %s: destination %q is no longer configured", mv.From, mv.Destination)
+					continue
+				}
+				fmt.Printf("  Moving %s -> %s\n", dest.Remote(mv.From), dest.Remote(mv.To))
+				if err := rc.Move(dest.Remote(mv.From), dest.Remote(mv.To)); err != nil {
+					// Already moved by an earlier, interrupted run?
+					if _, statErr := rc.Stat(dest.Remote(mv.To)); statErr != nil {
+						fail("move %s on %s: %v", mv.From, dest.Name, err)
+						continue
+					}
+				}
+				remote++
+			}
+			done = append(done, mv)
+		}
+		if len(done) > 0 {
+			if err := db.CompleteMoves(done); err != nil {
+				fail("%s: %v", done[0].From, err)
+			} else {
+				result.LocalMoved += local
+				result.RemoteMoved += remote
+			}
+		}
+		i = j
+	}
+
+	// Tidy up folders the old layout left empty.
+	syncDir := cfg.ExpandSyncDir()
+	for dir := range oldDirs {
+		for isWithin(syncDir, dir) && dir != syncDir && os.Remove(dir) == nil {
+			dir = filepath.Dir(dir)
+		}
+	}
+	return result
+}
+
+// survivesLocally reports whether a rather than b is the file now at a
+// local path whose contents hash to sum ("" if unknown).
+func survivesLocally(a, b manifest.Entry, sum string) bool {
+	if am, bm := sum != "" && a.SHA256 == sum, sum != "" && b.SHA256 == sum; am != bm {
+		return am
+	}
+	return a.PulledAt != nil && (b.PulledAt == nil || a.PulledAt.After(*b.PulledAt))
+}
+
+// moveLocal renames from to to, creating to's folder. It succeeds if an
+// earlier run already moved the file.
+func moveLocal(from, to string) error {
+	if err := os.MkdirAll(filepath.Dir(to), 0o755); err != nil {
+		return err
+	}
+	err := os.Rename(from, to)
+	if errors.Is(err, os.ErrNotExist) {
+		if _, statErr := os.Stat(to); statErr == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// isWithin reports whether p is inside dir.
+func isWithin(dir, p string) bool {
+	rel, err := filepath.Rel(dir, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
@@ -0,0 +1,118 @@
+package sync
+
+import (
+	"sort"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/manifest"
+)
+
+// StoragePath is the mount whose free space matters for recording.
+const StoragePath = "/sdcard"
+
+// Storage summarizes a headset's storage and how much of it FetchQuest
+// could give back.
+type Storage struct {
+	Total int64
+	Free  int64
+
+	MediaFiles int   // files under the configured media paths
+	MediaBytes int64 // bytes they take up
+
+	ReclaimableFiles int   // media files synced to every destination
+	ReclaimableBytes int64 // bytes `clean` could free right now
+}
+
+// DeviceStorage reports a device's free space, the bytes held by media
+// and the bytes held by media that is fully synced and safe to clean.
+func DeviceStorage(a *adb.Client, db *manifest.DB, cfg *config.Config, d adb.Device) (Storage, error) {
+	var s Storage
+	du, err := a.DiskUsage(d.Serial, StoragePath)
+	if err != nil {
+		return s, err
+	}
+	s.Total, s.Free = du.Total, du.Free
+
+	files, err := listMedia(a, cfg, d)
+	if err != nil {
+		return s, err
+	}
+	for _, f := range files {
+		s.MediaFiles++
+		s.MediaBytes += f.Size
+	}
+
+	destNames := make([]string, len(cfg.Destinations))
+	for i, dest := range cfg.Destinations {
+		destNames[i] = dest.Name
+	}
+	if len(destNames) == 0 {
+		return s, nil
+	}
+	synced, err := db.GetFullySyncedFiles(d.ID, destNames)
+	if err != nil {
+		return s, err
+	}
+	for _, e := range filterOnDevice(synced, files) {
+		s.ReclaimableFiles++
+		s.ReclaimableBytes += e.Size
+	}
+	return s, nil
+}
+
+// OnDevice returns the entries whose file is still on the device with the
+// recorded size, oldest first. Entries already cleaned are dropped, so
+// space estimates only count bytes a delete would really free.
+func OnDevice(a *adb.Client, cfg *config.Config, d adb.Device, entries []manifest.Entry) ([]manifest.Entry, error) {
+	files, err := listMedia(a, cfg, d)
+	if err != nil {
+		return nil, err
+	}
+	out := filterOnDevice(entries, files)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].MTime < out[j].MTime })
+	return out, nil
+}
+
+// SelectOldest picks entries from the front of entries (oldest first)
+// until their sizes add up to at least need bytes. It returns the picked
+// entries and their total size, which is less than need if there weren't
+// enough.
+func SelectOldest(entries []manifest.Entry, need int64) ([]manifest.Entry, int64) {
+	var picked []manifest.Entry
+	var total int64
+	for _, e := range entries {
+		if total >= need {
+			break
+		}
+		picked = append(picked, e)
+		total += e.Size
+	}
+	return picked, total
+}
+
+func listMedia(a *adb.Client, cfg *config.Config, d adb.Device) ([]adb.FileInfo, error) {
+	var all []adb.FileInfo
+	for _, mp := range cfg.MediaPaths {
+		files, err := a.ListFilesRecursive(d.Serial, mp)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, files...)
+	}
+	return all, nil
+}
+
+func filterOnDevice(entries []manifest.Entry, files []adb.FileInfo) []manifest.Entry {
+	sizes := make(map[string]int64, len(files))
+	for _, f := range files {
+		sizes[f.Path] = f.Size
+	}
+	var out []manifest.Entry
+	for _, e := range entries {
+		if size, ok := sizes[e.RemotePath]; ok && size == e.Size {
+			out = append(out, e)
+		}
+	}
+	return out
+}
@@ -1,6 +1,7 @@
 package sync
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -18,6 +19,8 @@ type Streamer struct {
 	Manifest  *manifest.DB
 	Config    *config.Config
 	SkipLocal bool
+	Power     *PowerGuard // optional battery check and stay-awake
+	Apps      AppFilter   // only stream captures from these apps; nil for all
 }
 
 // StreamResult summarizes a stream operation.
@@ -35,14 +38,14 @@ func (s *Streamer) StreamAll() ([]StreamResult, error) {
 		return nil, err
 	}
 	var results []StreamResult
-	for _, d := range devices {
+	for _, d := range adb.UniqueDevices(devices) {
 		if !d.IsOnline() {
 			continue
 		}
-		r, err := s.StreamDevice(d.Serial)
+		r, err := s.StreamDevice(d)
 		if err != nil {
 			results = append(results, StreamResult{
-				DeviceSerial: d.Serial,
+				DeviceSerial: d.ID,
 				Errors:       []string{err.Error()},
 			})
 			continue
@@ -53,8 +56,8 @@ func (s *Streamer) StreamAll() ([]StreamResult, error) {
 }
 
 // StreamDevice streams files from a specific device, one at a time.
-func (s *Streamer) StreamDevice(serial string) (StreamResult, error) {
-	result := StreamResult{DeviceSerial: serial}
+func (s *Streamer) StreamDevice(d adb.Device) (StreamResult, error) {
+	result := StreamResult{DeviceSerial: d.ID}
 
 	// Pre-check which destinations are reachable
 	var reachableDests []config.Destination
@@ -71,6 +74,14 @@ func (s *Streamer) StreamDevice(serial string) (StreamResult, error) {
 		return result, fmt.Errorf("no destinations are reachable")
 	}
 
+	if s.Power != nil {
+		release, err := s.Power.Acquire(d)
+		if err != nil {
+			return result, err
+		}
+		defer release()
+	}
+
 	var baseDir string
 	var cleanupDir string
 	if s.SkipLocal {
@@ -94,17 +105,24 @@ func (s *Streamer) StreamDevice(serial string) (StreamResult, error) {
 		Rclone:   s.Rclone,
 		Manifest: s.Manifest,
 		Config:   &streamConfig,
+		Apps:     s.Apps,
 	}
 
 	for _, mediaPath := range s.Config.MediaPaths {
-		files, err := s.ADB.ListFilesRecursive(serial, mediaPath)
+		files, err := s.ADB.ListFilesRecursive(d.Serial, mediaPath)
 		if err != nil {
 			result.Errors = append(result.Errors, fmt.Sprintf("list %s: %v", mediaPath, err))
 			continue
 		}
+		if err := labelNewApps(s.ADB, s.Manifest, d, files); err != nil {
+			fmt.Printf("  Could not look up app names: %v\n", err)
+		}
 
 		for _, f := range files {
-			pulled, err := s.Manifest.IsPulled(serial, f.Path, f.Size, f.MTime.Unix())
+			if !s.Apps.Match(manifest.Entry{RemotePath: f.Path}) {
+				continue
+			}
+			pulled, err := s.Manifest.IsPulled(d.ID, f.Path, f.Size, f.MTime.Unix())
 			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("check %s: %v", f.Path, err))
 				continue
@@ -115,16 +133,20 @@ func (s *Streamer) StreamDevice(serial string) (StreamResult, error) {
 			}
 
 			// Pull to local location
-			mediaType := mediaTypeFromPath(mediaPath)
-			localDir := filepath.Join(baseDir, mediaType)
-			if err := os.MkdirAll(localDir, 0o755); err != nil {
-				result.Errors = append(result.Errors, fmt.Sprintf("mkdir %s: %v", localDir, err))
+			localPath, err := PullPathFor(context.Background(), s.ADB, d.Serial, s.Manifest, s.Config, baseDir,
+				manifest.Entry{DeviceSerial: d.ID, RemotePath: f.Path, Size: f.Size, MTime: f.MTime.Unix()})
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("place %s: %v", f.Path, err))
+				continue
+			}
+			if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("mkdir %s: %v", filepath.Dir(localPath), err))
 				continue
 			}
-			localPath := filepath.Join(localDir, filepath.Base(f.Path))
 
 			fmt.Printf("  [stream] Pulling %s\n", f.Path)
-			if err := s.ADB.Pull(serial, f.Path, localPath); err != nil {
+			sum, err := FetchFile(context.Background(), s.ADB, d.Serial, f, localPath, nil)
+			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("pull %s: %v", f.Path, err))
 				continue
 			}
@@ -134,20 +156,34 @@ func (s *Streamer) StreamDevice(serial string) (StreamResult, error) {
 				result.Errors = append(result.Errors, fmt.Sprintf("chtimes %s: %v", localPath, err))
 			}
 
+			localPath, md, err := Settle(s.Manifest, s.Config, baseDir,
+				manifest.Entry{DeviceSerial: d.ID, RemotePath: f.Path, Size: f.Size, MTime: f.MTime.Unix(), SHA256: sum}, localPath)
+			if err != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("place %s: %v", f.Path, err))
+			}
+
 			// Record in manifest
 			manifestLocalPath := localPath
 			if s.SkipLocal {
 				manifestLocalPath = "" // temp file will be deleted
 			}
-			fileID, err := s.Manifest.RecordPull(serial, f.Path, manifestLocalPath, f.Size, f.MTime.Unix())
+			fileID, err := s.Manifest.RecordPull(d.ID, f.Path, manifestLocalPath, f.Size, f.MTime.Unix(), sum)
 			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("record %s: %v", f.Path, err))
 				continue
 			}
+			if !md.IsZero() {
+				if err := s.Manifest.RecordMedia(fileID, md); err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("record %s: %v", f.Path, err))
+				}
+			}
 
 			// Push to all destinations
 			fmt.Printf("  [stream] Pushing %s to all destinations\n", filepath.Base(f.Path))
-			pushResults, err := pusher.PushFile(fileID, localPath, baseDir)
+			pushResults, err := pusher.PushFile(manifest.Entry{
+				ID: fileID, DeviceSerial: d.ID, RemotePath: f.Path, LocalPath: localPath,
+				Size: f.Size, MTime: f.MTime.Unix(), SHA256: sum, Media: md,
+			}, baseDir)
 			if err != nil {
 				result.Errors = append(result.Errors, fmt.Sprintf("push %s: %v", f.Path, err))
 				continue
@@ -173,5 +209,9 @@ func (s *Streamer) StreamDevice(serial string) (StreamResult, error) {
 			result.FilesStreamed++
 		}
 	}
+
+	if !s.SkipLocal {
+		CleanPartials(baseDir)
+	}
 	return result, nil
 }
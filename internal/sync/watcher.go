@@ -0,0 +1,122 @@
+package sync
+
+import (
+	"context"
+	gosync "sync"
+	"time"
+
+	"github.com/FluidXR/fetchquest/internal/adb"
+)
+
+// DefaultDebounce is how long a headset must stay connected before the
+// watcher syncs it. Plugging a cable in, authorizing, or adbd restarting
+// all make a device flap between states for a few seconds.
+const DefaultDebounce = 5 * time.Second
+
+// Watcher runs a sync whenever a headset comes online.
+type Watcher struct {
+	ADB *adb.Client
+	// Debounce is how long a device must stay online before OnConnect
+	// runs. Defaults to DefaultDebounce.
+	Debounce time.Duration
+	// Known reports whether a device should be synced. Nil means all.
+	Known func(adb.Device) bool
+	// OnConnect syncs one device. Calls are serialized, and a device is
+	// never queued twice.
+	OnConnect func(ctx context.Context, d adb.Device)
+	// OnChange, if set, is called with the deduplicated device list
+	// whenever it changes.
+	OnChange func(devs []adb.Device)
+}
+
+// Run watches for devices until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) error {
+	debounce := w.Debounce
+	if debounce <= 0 {
+		debounce = DefaultDebounce
+	}
+
+	var mu gosync.Mutex
+	online := make(map[string]adb.Device) // device ID -> preferred transport
+	timers := make(map[string]*time.Timer)
+	pending := make(map[string]bool) // queued or running
+	queue := make(chan string, 16)
+
+	// A single worker keeps syncs from competing for the same destinations.
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case id := <-queue:
+				mu.Lock()
+				d, ok := online[id]
+				mu.Unlock()
+				if ok {
+					w.OnConnect(ctx, d)
+				}
+				mu.Lock()
+				delete(pending, id)
+				mu.Unlock()
+			}
+		}
+	}()
+
+	err := w.ADB.TrackDevices(ctx, func(devs []adb.Device) {
+		unique := adb.UniqueDevices(devs)
+		if w.OnChange != nil {
+			w.OnChange(unique)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		now := make(map[string]adb.Device)
+		for _, d := range unique {
+			if d.IsOnline() {
+				now[d.ID] = d
+			}
+		}
+		for id := range online {
+			if _, ok := now[id]; !ok {
+				// Went away before settling: forget it.
+				if t := timers[id]; t != nil {
+					t.Stop()
+					delete(timers, id)
+				}
+			}
+		}
+		for id, d := range now {
+			if _, was := online[id]; was {
+				continue
+			}
+			if w.Known != nil && !w.Known(d) {
+				continue
+			}
+			if t := timers[id]; t != nil {
+				t.Stop()
+			}
+			timers[id] = time.AfterFunc(debounce, func() {
+				mu.Lock()
+				defer mu.Unlock()
+				delete(timers, id)
+				if _, ok := online[id]; !ok || pending[id] {
+					return
+				}
+				pending[id] = true
+				select {
+				case queue <- id:
+				default:
+					delete(pending, id) // queue full; the next connect retries
+				}
+			})
+		}
+		online = now
+	})
+
+	mu.Lock()
+	for _, t := range timers {
+		t.Stop()
+	}
+	mu.Unlock()
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -16,9 +17,12 @@ import (
 	"time"
 
 	"github.com/FluidXR/fetchquest/internal/adb"
+	"github.com/FluidXR/fetchquest/internal/backup"
 	"github.com/FluidXR/fetchquest/internal/config"
+	"github.com/FluidXR/fetchquest/internal/discovery"
 	"github.com/FluidXR/fetchquest/internal/manifest"
 	"github.com/FluidXR/fetchquest/internal/rclone"
+	qsync "github.com/FluidXR/fetchquest/internal/sync"
 	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
@@ -27,11 +31,17 @@ type App struct {
 	ctx        context.Context
 	syncCancel context.CancelFunc
 	syncMu     gosync.Mutex
+
+	watchCancel context.CancelFunc // stops the auto-sync watcher
+	watchMu     gosync.Mutex
 }
 
 // SetContext is called by Wails on startup. Do not call from the frontend.
 func (a *App) SetContext(ctx context.Context) {
 	a.ctx = ctx
+	if cfg, err := config.Load(); err == nil && cfg.AutoSync {
+		a.startWatcher()
+	}
 }
 
 // NewApp returns an App instance for Wails binding.
@@ -46,6 +56,10 @@ type DeviceInfo struct {
 	Status   string `json:"status"`
 	Nickname string `json:"nickname"`
 	Stats    string `json:"stats"`
+	Free     int64  `json:"free"`     // bytes free on /sdcard, 0 if unknown
+	Total    int64  `json:"total"`    // size of /sdcard in bytes
+	Battery  int    `json:"battery"`  // charge in percent, -1 if unknown
+	Charging bool   `json:"charging"` // plugged into a power source
 }
 
 // DepInfo describes a missing dependency.
@@ -70,6 +84,7 @@ type ConfigSummary struct {
 	HasDestinations  bool               `json:"hasDestinations"`
 	AdbPath          string             `json:"adbPath"`
 	RclonePath       string             `json:"rclonePath"`
+	AutoSync         bool               `json:"autoSync"`
 }
 
 // GetDevices returns connected Quest devices and their sync stats.
@@ -80,6 +95,7 @@ func (a *App) GetDevices() ([]DeviceInfo, error) {
 	}
 
 	adbClient := adb.NewClient(cfg.AdbPath)
+	adbClient.ConnectAll(cfg.WiFiAddrs())
 	devs, err := adbClient.Devices()
 	if err != nil {
 		return nil, err
@@ -91,12 +107,20 @@ func (a *App) GetDevices() ([]DeviceInfo, error) {
 	}
 	defer db.Close()
 
+	if changed, err := qsync.AdoptDeviceIdentities(db, cfg, devs); err != nil {
+		return nil, err
+	} else if changed {
+		if err := config.Save(cfg); err != nil {
+			return nil, err
+		}
+	}
+
 	numDests := len(cfg.Destinations)
 	var result []DeviceInfo
 
-	for _, d := range devs {
+	for _, d := range adb.UniqueDevices(devs) {
 		nickname := ""
-		if dc, ok := cfg.Devices[d.Serial]; ok && dc.Nickname != "" {
+		if dc, ok := cfg.Devices[d.ID]; ok && dc.Nickname != "" {
 			nickname = dc.Nickname
 		}
 
@@ -106,14 +130,20 @@ func (a *App) GetDevices() ([]DeviceInfo, error) {
 		}
 
 		stats := ""
+		var du adb.DiskUsage
+		battery := adb.Battery{Level: -1}
 		if d.IsOnline() {
+			du, _ = adbClient.DiskUsage(d.Serial, qsync.StoragePath)
+			if b, err := adbClient.Battery(d.Serial); err == nil {
+				battery = b
+			}
 			onDevice := 0
 			for _, mp := range cfg.MediaPaths {
 				if files, err := adbClient.ListFilesRecursive(d.Serial, mp); err == nil {
 					onDevice += len(files)
 				}
 			}
-			if s, err := db.GetDeviceStats(d.Serial, numDests); err == nil {
+			if s, err := db.GetDeviceStats(d.ID, numDests); err == nil {
 				stats = fmt.Sprintf("%d on device · %d backed up", onDevice, s.SyncedFiles)
 			} else if onDevice > 0 {
 				stats = fmt.Sprintf("%d on device", onDevice)
@@ -121,11 +151,15 @@ func (a *App) GetDevices() ([]DeviceInfo, error) {
 		}
 
 		result = append(result, DeviceInfo{
-			Serial:   d.Serial,
+			Serial:   d.ID,
 			Model:    d.Model,
 			Status:   status,
 			Nickname: nickname,
 			Stats:    stats,
+			Free:     du.Free,
+			Total:    du.Total,
+			Battery:  battery.Level,
+			Charging: battery.Charging,
 		})
 	}
 
@@ -163,6 +197,7 @@ func (a *App) GetConfig() (ConfigSummary, error) {
 		HasDestinations:  len(cfg.Destinations) > 0,
 		AdbPath:          cfg.AdbPath,
 		RclonePath:       cfg.RclonePath,
+		AutoSync:         cfg.AutoSync,
 	}, nil
 }
 
@@ -526,6 +561,11 @@ func (a *App) CancelSync() {
 func (a *App) Sync(skipLocal bool) (string, error) {
 	syncCtx, cancel := context.WithCancel(context.Background())
 	a.syncMu.Lock()
+	if a.syncCancel != nil {
+		a.syncMu.Unlock()
+		cancel()
+		return "", fmt.Errorf("a sync is already running")
+	}
 	a.syncCancel = cancel
 	a.syncMu.Unlock()
 	defer func() {
@@ -560,6 +600,7 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 
 	// ── Phase 1: Pull ──
 
+	adbClient.ConnectAll(cfg.WiFiAddrs())
 	devs, err := adbClient.Devices()
 	if err != nil {
 		return "", err
@@ -567,19 +608,42 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 
 	// First scan all devices to find new files
 	type pendingFile struct {
-		serial    string
-		mediaPath string
-		info      adb.FileInfo
+		serial string // transport serial, for adb
+		id     string // hardware serial, for the manifest
+		info   adb.FileInfo
 	}
 	var toPull []pendingFile
 
-	for _, d := range devs {
+	// Headsets are kept awake until the pull phase is over; low-battery
+	// ones that aren't charging are skipped.
+	guard := &qsync.PowerGuard{ADB: adbClient, MinBattery: cfg.MinBattery}
+	var releases []func()
+	releaseAll := func() {
+		for _, release := range releases {
+			release()
+		}
+		releases = nil
+	}
+	defer releaseAll()
+	var skipped []string
+
+	for _, d := range adb.UniqueDevices(devs) {
 		if syncCtx.Err() != nil {
 			break
 		}
 		if !d.IsOnline() {
 			continue
 		}
+		release, err := guard.Acquire(d)
+		if err != nil {
+			name := d.Model
+			if dc := cfg.Devices[d.ID]; dc.Nickname != "" {
+				name = dc.Nickname
+			}
+			skipped = append(skipped, fmt.Sprintf("Skipped %s: %v — plug it in to sync.", name, err))
+			continue
+		}
+		releases = append(releases, release)
 		emit(SyncProgress{Phase: "scan", File: d.Model, Current: 0, Total: 0})
 		for _, mp := range cfg.MediaPaths {
 			if syncCtx.Err() != nil {
@@ -590,9 +654,9 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 				continue
 			}
 			for _, f := range files {
-				pulled, _ := db.IsPulled(d.Serial, f.Path, f.Size, f.MTime.Unix())
+				pulled, _ := db.IsPulled(d.ID, f.Path, f.Size, f.MTime.Unix())
 				if !pulled {
-					toPull = append(toPull, pendingFile{serial: d.Serial, mediaPath: mp, info: f})
+					toPull = append(toPull, pendingFile{serial: d.Serial, id: d.ID, info: f})
 				}
 			}
 		}
@@ -620,55 +684,51 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 		fname := filepath.Base(pf.info.Path)
 		emit(SyncProgress{Phase: "pull", File: fname, Current: i + 1, Total: len(toPull), FilePercent: 0})
 
-		mediaType := classifyMediaPath(pf.mediaPath)
-		localDir := filepath.Join(pullDir, mediaType)
-		if err := os.MkdirAll(localDir, 0o755); err != nil {
+		localPath, err := qsync.PullPathFor(syncCtx, adbClient, pf.serial, db, cfg, pullDir,
+			manifest.Entry{DeviceSerial: pf.id, RemotePath: pf.info.Path, Size: pf.info.Size, MTime: pf.info.MTime.Unix()})
+		if err != nil {
 			continue
 		}
-		localPath := filepath.Join(localDir, fname)
-
-		// Run adb pull with file-size progress monitoring
-		cmd := newCmdContext(syncCtx, adbClient.Bin(), "-s", pf.serial, "pull", pf.info.Path, localPath)
-		if err := cmd.Start(); err != nil {
+		if err := os.MkdirAll(filepath.Dir(localPath), 0o755); err != nil {
 			continue
 		}
-		done := make(chan struct{})
-		go func() {
-			ticker := time.NewTicker(300 * time.Millisecond)
-			defer ticker.Stop()
-			for {
-				select {
-				case <-done:
-					return
-				case <-ticker.C:
-					if info, err := os.Stat(localPath); err == nil && pf.info.Size > 0 {
-						pct := int(info.Size() * 100 / pf.info.Size)
-						if pct > 100 {
-							pct = 100
-						}
-						emit(SyncProgress{Phase: "pull", File: fname, Current: i + 1, Total: len(toPull), FilePercent: pct})
-					}
-				}
+
+		// Pull with byte-level progress reported by the ADB client
+		var lastPct int
+		sum, pullErr := qsync.FetchFile(syncCtx, adbClient, pf.serial, pf.info, localPath, func(written int64) {
+			if pf.info.Size <= 0 {
+				return
+			}
+			pct := int(written * 100 / pf.info.Size)
+			if pct > 100 {
+				pct = 100
 			}
-		}()
-		pullErr := cmd.Wait()
-		close(done)
+			if pct != lastPct {
+				lastPct = pct
+				emit(SyncProgress{Phase: "pull", File: fname, Current: i + 1, Total: len(toPull), FilePercent: pct})
+			}
+		})
 		if pullErr != nil {
 			continue
 		}
 
 		emit(SyncProgress{Phase: "pull", File: fname, Current: i + 1, Total: len(toPull), FilePercent: 100})
 		_ = os.Chtimes(localPath, pf.info.MTime, pf.info.MTime)
+		localPath, md, _ := qsync.Settle(db, cfg, pullDir,
+			manifest.Entry{DeviceSerial: pf.id, RemotePath: pf.info.Path, Size: pf.info.Size, MTime: pf.info.MTime.Unix(), SHA256: sum}, localPath)
 
 		// In skip-local mode, record empty local path (temp file will be cleaned up)
 		manifestLocalPath := localPath
 		if skipLocal {
 			manifestLocalPath = ""
 		}
-		fileID, err := db.RecordPull(pf.serial, pf.info.Path, manifestLocalPath, pf.info.Size, pf.info.MTime.Unix())
+		fileID, err := db.RecordPull(pf.id, pf.info.Path, manifestLocalPath, pf.info.Size, pf.info.MTime.Unix(), sum)
 		if err != nil {
 			continue
 		}
+		if !md.IsZero() {
+			_ = db.RecordMedia(fileID, md)
+		}
 
 		// In skip-local mode, push immediately after pulling each file
 		if skipLocal {
@@ -676,12 +736,13 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 				if rc == nil || !rc.IsReachable(dest.RcloneRemote) {
 					continue
 				}
-				mediaType := classifyMediaPath(pf.mediaPath)
-				remoteDest := dest.RcloneRemote
-				if !strings.HasSuffix(remoteDest, "/") {
-					remoteDest += "/"
+				objectPath, err := qsync.NewObjectPath(db, cfg, dest, pullDir, manifest.Entry{
+					ID: fileID, DeviceSerial: pf.id, RemotePath: pf.info.Path, LocalPath: localPath, MTime: pf.info.MTime.Unix(), Media: md,
+				})
+				if err != nil {
+					continue
 				}
-				remoteDest += mediaType + "/" + fname
+				remoteDest := dest.Remote(objectPath)
 
 				pushCmd := newCmd(rc.Bin(), "copyto",
 					"--stats-one-line", "--stats", "1s",
@@ -707,7 +768,12 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 				if err := pushCmd.Wait(); err != nil {
 					continue
 				}
-				_ = db.RecordDestSync(fileID, dest.Name)
+				ds, err := qsync.VerifyUpload(rc, dest, objectPath, pf.info.Size, sum)
+				if err != nil {
+					continue
+				}
+				ds.FileID = fileID
+				_ = db.RecordDestSync(ds)
 			}
 			// Delete temp file after pushing
 			os.Remove(localPath)
@@ -715,6 +781,10 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 
 		totalPulled++
 	}
+	releaseAll()
+	if !skipLocal {
+		qsync.CleanPartials(syncDir)
+	}
 
 	// ── Phase 2: Push (only in normal mode — skip-local pushes inline above) ──
 
@@ -742,15 +812,11 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 				fname := filepath.Base(entry.LocalPath)
 				emit(SyncProgress{Phase: "push", File: fname, Current: i + 1, Total: len(unpushed), FilePercent: 0})
 
-				relPath, err := filepath.Rel(syncDir, entry.LocalPath)
+				objectPath, err := qsync.NewObjectPath(db, cfg, dest, syncDir, entry)
 				if err != nil {
-					relPath = fname
-				}
-				remoteDest := dest.RcloneRemote
-				if !strings.HasSuffix(remoteDest, "/") {
-					remoteDest += "/"
+					continue
 				}
-				remoteDest += filepath.ToSlash(relPath)
+				remoteDest := dest.Remote(objectPath)
 
 				// Run rclone with stats as log lines to stderr (not -P which needs a terminal)
 				cmd := newCmdContext(syncCtx, rc.Bin(), "copyto",
@@ -778,8 +844,17 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 				if err := cmd.Wait(); err != nil {
 					continue
 				}
+				sum := entry.SHA256
+				if sum == "" {
+					sum, _ = qsync.HashFile(entry.LocalPath)
+				}
+				ds, err := qsync.VerifyUpload(rc, dest, objectPath, entry.Size, sum)
+				if err != nil {
+					continue
+				}
 				emit(SyncProgress{Phase: "push", File: fname, Current: i + 1, Total: len(unpushed), FilePercent: 100})
-				_ = db.RecordDestSync(entry.ID, dest.Name)
+				ds.FileID = entry.ID
+				_ = db.RecordDestSync(ds)
 				totalPushed++
 			}
 		}
@@ -794,7 +869,11 @@ func (a *App) Sync(skipLocal bool) (string, error) {
 	if len(devs) == 0 {
 		return "No connected devices found. Plug in your Quest via USB.", nil
 	}
-	return fmt.Sprintf("Pulled %d new files, uploaded %d to destinations.", totalPulled, totalPushed), nil
+	msg := fmt.Sprintf("Pulled %d new files, uploaded %d to destinations.", totalPulled, totalPushed)
+	if len(skipped) > 0 {
+		msg += " " + strings.Join(skipped, " ")
+	}
+	return msg, nil
 }
 
 // scanCR is a bufio.SplitFunc that splits on \r or \n (rclone uses \r for progress).
@@ -829,36 +908,23 @@ func parseRclonePercent(line string) int {
 	return pct
 }
 
-// classifyMediaPath returns a friendly label from a Quest media path.
-func classifyMediaPath(path string) string {
-	lower := strings.ToLower(path)
-	switch {
-	case strings.Contains(lower, "videoshots"):
-		return "Videos"
-	case strings.Contains(lower, "screenshots"):
-		return "Screenshots"
-	case strings.Contains(lower, "photos"):
-		return "Photos"
-	default:
-		return "Other"
-	}
-}
-
 func backupManifest(db *manifest.DB, cfg *config.Config, rc *rclone.Client) {
 	if len(cfg.Destinations) == 0 {
 		return
 	}
-	dbPath := db.Path()
-	if _, err := os.Stat(dbPath); err != nil {
+	machine, err := config.MachineID()
+	if err != nil {
 		return
 	}
+	_, _ = backup.MergeOthers(rc, db, cfg.Destinations, machine)
+	snap, err := backup.Snapshot(db)
+	if err != nil {
+		return
+	}
+	defer os.Remove(snap)
+	now := time.Now()
 	for _, dest := range cfg.Destinations {
-		remote := dest.RcloneRemote
-		if !strings.HasSuffix(remote, "/") {
-			remote += "/"
-		}
-		remote += ".fetchquest/manifest.db"
-		_ = rc.Copy(dbPath, remote)
+		_, _ = backup.Upload(rc, dest, snap, machine, now, cfg.BackupKeep)
 	}
 }
 
@@ -900,12 +966,12 @@ func (a *App) PreviewSync() (PreviewResult, error) {
 	var result PreviewResult
 
 	// Scan each online device for new (unpulled) files
-	for _, d := range devs {
+	for _, d := range adb.UniqueDevices(devs) {
 		if !d.IsOnline() {
 			continue
 		}
 		nickname := ""
-		if dc, ok := cfg.Devices[d.Serial]; ok && dc.Nickname != "" {
+		if dc, ok := cfg.Devices[d.ID]; ok && dc.Nickname != "" {
 			nickname = dc.Nickname
 		}
 		label := nickname
@@ -913,7 +979,7 @@ func (a *App) PreviewSync() (PreviewResult, error) {
 			label = d.Model
 		}
 		if label == "" {
-			label = d.Serial
+			label = d.ID
 		}
 
 		newCount := 0
@@ -923,7 +989,7 @@ func (a *App) PreviewSync() (PreviewResult, error) {
 				continue
 			}
 			for _, f := range files {
-				pulled, _ := db.IsPulled(d.Serial, f.Path, f.Size, f.MTime.Unix())
+				pulled, _ := db.IsPulled(d.ID, f.Path, f.Size, f.MTime.Unix())
 				if !pulled {
 					newCount++
 				}
@@ -954,6 +1020,10 @@ type FileEntry struct {
 	LocalPath  string   `json:"localPath"`
 	Size       int64    `json:"size"`
 	MTime      int64    `json:"mtime"`
+	CapturedAt int64    `json:"capturedAt"` // from the file itself, 0 if unknown
+	Duration   float64  `json:"duration"`   // seconds
+	Width      int      `json:"width"`
+	Height     int      `json:"height"`
 	MediaType  string   `json:"mediaType"`
 	IsPulled   bool     `json:"isPulled"`
 	SyncedDests []string `json:"syncedDests"`
@@ -965,6 +1035,8 @@ type DestinationStatus struct {
 	Name      string `json:"name"`
 	Reachable bool   `json:"reachable"`
 	FileCount int    `json:"fileCount"`
+	Queued    int    `json:"queued"`    // local files not yet pushed here
+	NextRetry int64  `json:"nextRetry"` // unix time the daemon retries, 0 if not waiting
 }
 
 // classifyMedia returns a media type label based on file extension.
@@ -988,20 +1060,23 @@ func (a *App) GetDeviceFiles(serial string) ([]FileEntry, error) {
 	}
 
 	adbClient := adb.NewClient(cfg.AdbPath)
+	var dev adb.Device
 	if serial == "" {
 		devs, err := adbClient.Devices()
 		if err != nil {
 			return nil, err
 		}
-		for _, d := range devs {
+		for _, d := range adb.UniqueDevices(devs) {
 			if d.IsOnline() {
-				serial = d.Serial
+				dev = d
 				break
 			}
 		}
-		if serial == "" {
+		if dev.Serial == "" {
 			return nil, fmt.Errorf("no device connected")
 		}
+	} else if dev, err = adbClient.FindDevice(serial); err != nil {
+		return nil, err
 	}
 
 	db, err := manifest.Open(config.ConfigDir())
@@ -1012,7 +1087,7 @@ func (a *App) GetDeviceFiles(serial string) ([]FileEntry, error) {
 
 	var allFiles []adb.FileInfo
 	for _, mp := range cfg.MediaPaths {
-		files, err := adbClient.ListFilesRecursive(serial, mp)
+		files, err := adbClient.ListFilesRecursive(dev.Serial, mp)
 		if err != nil {
 			continue
 		}
@@ -1024,24 +1099,39 @@ func (a *App) GetDeviceFiles(serial string) ([]FileEntry, error) {
 		destNames[i] = d.Name
 	}
 
+	// Capture metadata is only known for files already pulled.
+	known := make(map[string]manifest.Media)
+	if files, err := db.AllFiles(); err == nil {
+		for _, e := range files {
+			if e.DeviceSerial == dev.ID {
+				known[e.RemotePath] = e.Media
+			}
+		}
+	}
+
 	var entries []FileEntry
 	for _, f := range allFiles {
-		pulled, _ := db.IsPulled(serial, f.Path, f.Size, f.MTime.Unix())
+		pulled, _ := db.IsPulled(dev.ID, f.Path, f.Size, f.MTime.Unix())
 		var syncedDests []string
 		if pulled {
 			// Check which destinations this file has been synced to
 			for _, dn := range destNames {
-				synced, _ := db.IsFullySynced(serial, f.Path, []string{dn})
+				synced, _ := db.IsFullySynced(dev.ID, f.Path, []string{dn})
 				if synced {
 					syncedDests = append(syncedDests, dn)
 				}
 			}
 		}
+		md := known[f.Path]
 		entries = append(entries, FileEntry{
 			FileName:    filepath.Base(f.Path),
 			Path:        f.Path,
 			Size:        f.Size,
 			MTime:       f.MTime.Unix(),
+			CapturedAt:  md.CapturedAt,
+			Duration:    float64(md.DurationMS) / 1000,
+			Width:       md.Width,
+			Height:      md.Height,
 			MediaType:   classifyMedia(f.Path),
 			IsPulled:    pulled,
 			SyncedDests: syncedDests,
@@ -1079,9 +1169,9 @@ func (a *App) GetLocalFiles() ([]FileEntry, error) {
 		if err != nil || info.IsDir() {
 			return nil
 		}
-		// Skip hidden files and manifest
+		// Skip hidden files, manifest and unfinished pulls
 		base := filepath.Base(path)
-		if strings.HasPrefix(base, ".") {
+		if strings.HasPrefix(base, ".") || strings.HasSuffix(base, qsync.PartialSuffix) {
 			return nil
 		}
 
@@ -1090,7 +1180,11 @@ func (a *App) GetLocalFiles() ([]FileEntry, error) {
 
 		// Look up sync status in manifest by local_path
 		var syncedDests []string
+		var md manifest.Media
 		rows, qerr := db.QueryByLocalPath(path)
+		if qerr == nil && len(rows) > 0 {
+			md = rows[0].Media
+		}
 		if qerr == nil && rows != nil {
 			for _, dn := range destNames {
 				for _, row := range rows {
@@ -1109,6 +1203,10 @@ func (a *App) GetLocalFiles() ([]FileEntry, error) {
 			LocalPath:   path,
 			Size:        info.Size(),
 			MTime:       info.ModTime().Unix(),
+			CapturedAt:  md.CapturedAt,
+			Duration:    float64(md.DurationMS) / 1000,
+			Width:       md.Width,
+			Height:      md.Height,
 			MediaType:   mediaType,
 			IsPulled:    true,
 			SyncedDests: syncedDests,
@@ -1124,6 +1222,7 @@ type CleanPreviewResult struct {
 	Eligible  int   `json:"eligible"`  // files that would be deleted
 	Unsynced  int   `json:"unsynced"`  // files NOT yet fully backed up
 	TotalSize int64 `json:"totalSize"` // total bytes of eligible files
+	Verified  int   `json:"verified"`  // eligible files hash-verified at every destination
 }
 
 func (a *App) PreviewClean() (CleanPreviewResult, error) {
@@ -1152,19 +1251,22 @@ func (a *App) PreviewClean() (CleanPreviewResult, error) {
 		destNames[i] = d.Name
 	}
 
-	for _, dev := range devs {
+	for _, dev := range adb.UniqueDevices(devs) {
 		if !dev.IsOnline() {
 			continue
 		}
-		synced, err := db.GetFullySyncedFiles(dev.Serial, destNames)
+		synced, err := db.GetFullySyncedFiles(dev.ID, destNames)
 		if err != nil {
 			continue
 		}
 		result.Eligible += len(synced)
 		for _, e := range synced {
 			result.TotalSize += e.Size
+			if ok, _ := db.IsHashVerified(e.ID, destNames); ok {
+				result.Verified++
+			}
 		}
-		stats, err := db.GetDeviceStats(dev.Serial, len(destNames))
+		stats, err := db.GetDeviceStats(dev.ID, len(destNames))
 		if err != nil {
 			continue
 		}
@@ -1201,11 +1303,11 @@ func (a *App) CleanQuest() (string, error) {
 	}
 
 	totalDeleted := 0
-	for _, dev := range devs {
+	for _, dev := range adb.UniqueDevices(devs) {
 		if !dev.IsOnline() {
 			continue
 		}
-		entries, err := db.GetFullySyncedFiles(dev.Serial, destNames)
+		entries, err := db.GetFullySyncedFiles(dev.ID, destNames)
 		if err != nil {
 			continue
 		}
@@ -1299,6 +1401,12 @@ func (a *App) GetDestinationStatuses() ([]DestinationStatus, error) {
 		if err := db.CountSyncedTo(d.Name, &count); err == nil {
 			statuses[i].FileCount = count
 		}
+		if st, err := db.GetDestStatuses([]string{d.Name}); err == nil && len(st) == 1 {
+			statuses[i].Queued = st[0].Queued
+			if st[0].NextRetry != nil && time.Until(*st[0].NextRetry) > 0 {
+				statuses[i].NextRetry = st[0].NextRetry.Unix()
+			}
+		}
 
 		// Check reachability concurrently
 		if rc != nil {
@@ -1324,6 +1432,86 @@ func (a *App) SetToolPaths(adbPath, rclonePath string) error {
 	return config.Save(cfg)
 }
 
+// AutoSyncResult is sent to the frontend when an automatic sync finishes.
+type AutoSyncResult struct {
+	Message string `json:"message"`
+	Error   string `json:"error"`
+}
+
+// SetAutoSync turns syncing on connect on or off and saves the setting.
+func (a *App) SetAutoSync(enabled bool) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cfg.AutoSync = enabled
+	if err := config.Save(cfg); err != nil {
+		return err
+	}
+	if enabled {
+		a.startWatcher()
+	} else {
+		a.stopWatcher()
+	}
+	return nil
+}
+
+// startWatcher watches for headsets and runs a sync when one connects.
+// The frontend is told through events so it can show progress.
+func (a *App) startWatcher() {
+	a.watchMu.Lock()
+	defer a.watchMu.Unlock()
+	if a.watchCancel != nil {
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.watchCancel = cancel
+
+	w := &qsync.Watcher{
+		ADB: adb.NewClient(cfg.AdbPath),
+		OnChange: func([]adb.Device) {
+			if a.ctx != nil {
+				wailsruntime.EventsEmit(a.ctx, "devices:changed")
+			}
+		},
+		OnConnect: func(ctx context.Context, d adb.Device) {
+			if a.ctx == nil {
+				return
+			}
+			if cfg, err := config.Load(); err != nil || len(cfg.Destinations) == 0 {
+				return
+			}
+			a.syncMu.Lock()
+			busy := a.syncCancel != nil
+			a.syncMu.Unlock()
+			if busy {
+				return
+			}
+			wailsruntime.EventsEmit(a.ctx, "autosync:start", d.ID)
+			msg, err := a.Sync(false)
+			res := AutoSyncResult{Message: msg}
+			if err != nil {
+				res.Error = err.Error()
+			}
+			wailsruntime.EventsEmit(a.ctx, "autosync:done", res)
+		},
+	}
+	go w.Run(ctx)
+}
+
+func (a *App) stopWatcher() {
+	a.watchMu.Lock()
+	defer a.watchMu.Unlock()
+	if a.watchCancel != nil {
+		a.watchCancel()
+		a.watchCancel = nil
+	}
+}
+
 // SetDeviceNickname saves a nickname for a device serial.
 func (a *App) SetDeviceNickname(serial, nickname string) error {
 	serial = strings.TrimSpace(serial)
@@ -1343,6 +1531,118 @@ func (a *App) SetDeviceNickname(serial, nickname string) error {
 	return config.Save(cfg)
 }
 
+// DiscoveredDevice is a headset advertising wireless ADB on the LAN.
+type DiscoveredDevice struct {
+	Serial    string `json:"serial"`
+	Addr      string `json:"addr"`
+	Service   string `json:"service"`
+	Nickname  string `json:"nickname"`
+	Connected bool   `json:"connected"`
+}
+
+// DiscoverDevices searches the local network for headsets with wireless ADB
+// enabled. Known headsets whose address changed are updated and reconnected.
+func (a *App) DiscoverDevices() ([]DiscoveredDevice, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	svcs, err := discovery.Browse(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	adbClient := adb.NewClient(cfg.AdbPath)
+	if changed := discovery.UpdateConfig(cfg, svcs); len(changed) > 0 {
+		if err := config.Save(cfg); err != nil {
+			return nil, err
+		}
+		var addrs []string
+		for _, serial := range changed {
+			addrs = append(addrs, cfg.Devices[serial].WiFiAddr())
+		}
+		adbClient.ConnectAll(addrs)
+	}
+
+	connected := make(map[string]bool)
+	if devs, err := adbClient.Devices(); err == nil {
+		for _, d := range devs {
+			if d.IsOnline() {
+				connected[d.ID] = true
+				connected[d.Serial] = true
+			}
+		}
+	}
+	result := []DiscoveredDevice{}
+	for _, s := range svcs {
+		serial := s.Serial
+		if serial == "" {
+			serial = s.Instance
+		}
+		result = append(result, DiscoveredDevice{
+			Serial:    serial,
+			Addr:      s.Addr(),
+			Service:   s.Type,
+			Nickname:  cfg.Devices[s.Serial].Nickname,
+			Connected: connected[s.Serial] || connected[s.Addr()],
+		})
+	}
+	return result, nil
+}
+
+// PairDevice pairs with a Quest over wireless debugging using the address
+// and code from its pairing dialog. connectAddr is the ip:port shown on the
+// Wireless debugging screen; when given it is saved so later scans reconnect.
+func (a *App) PairDevice(pairAddr, code, connectAddr string) (string, error) {
+	pairAddr = strings.TrimSpace(pairAddr)
+	code = strings.TrimSpace(code)
+	connectAddr = strings.TrimSpace(connectAddr)
+	if _, _, err := net.SplitHostPort(pairAddr); err != nil {
+		return "", fmt.Errorf("pairing address must be ip:port")
+	}
+	if code == "" {
+		return "", fmt.Errorf("pairing code is required")
+	}
+	var host string
+	var port int
+	if connectAddr != "" {
+		h, p, err := net.SplitHostPort(connectAddr)
+		if err != nil {
+			return "", fmt.Errorf("connect address must be ip:port")
+		}
+		if port, err = strconv.Atoi(p); err != nil {
+			return "", fmt.Errorf("invalid connect port %q", p)
+		}
+		host = h
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return "", err
+	}
+	adbClient := adb.NewClient(cfg.AdbPath)
+	d, err := adbClient.PairDevice(pairAddr, code, connectAddr)
+	if err != nil {
+		return "", err
+	}
+
+	dc := cfg.Devices[d.ID]
+	dc.PairedAddr = pairAddr
+	if host != "" {
+		dc.WiFiIP = host
+		dc.WiFiPort = port
+	}
+	cfg.Devices[d.ID] = dc
+	if err := config.Save(cfg); err != nil {
+		return "", err
+	}
+
+	name := d.Model
+	if name == "" {
+		name = d.ID
+	}
+	return fmt.Sprintf("Paired with %s.", name), nil
+}
+
 func checkDeps(cfg *config.Config) []DepInfo {
 	deps := []struct {
 		name       string
@@ -49,8 +49,14 @@
               : '<span class="dest-status-dot fail"></span> Portal Sealed';
           }
           var statsEl = document.getElementById('dest-stats-' + s.name);
-          if (statsEl && s.fileCount > 0) {
-            statsEl.textContent = s.fileCount + ' files synced';
+          if (statsEl) {
+            var parts = [];
+            if (s.fileCount > 0) parts.push(s.fileCount + ' files synced');
+            if (s.queued > 0) parts.push(s.queued + ' queued');
+            if (!s.reachable && s.nextRetry > 0) {
+              parts.push('retry ' + new Date(s.nextRetry * 1000).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' }));
+            }
+            if (parts.length > 0) statsEl.textContent = parts.join(' · ');
           }
         });
       });
@@ -7,6 +7,33 @@
   var activeTab = 'quest';
   var questTab = document.querySelector('.tab[data-tab="quest"]');
   var localTab = document.querySelector('.tab[data-tab="local"]');
+  var lastFiles = null;
+  var sortKey = 'date';
+  var sortDesc = true;
+
+  // Capture time read from the file, falling back to the headset's mtime.
+  function fileDate(f) { return f.capturedAt || f.mtime || 0; }
+
+  var sortValues = {
+    name: function (f) { return (f.fileName || '').toLowerCase(); },
+    date: fileDate,
+    length: function (f) { return f.duration || 0; },
+    resolution: function (f) { return (f.width || 0) * (f.height || 0); },
+    size: function (f) { return f.size || 0; }
+  };
+
+  function formatLength(seconds) {
+    if (!seconds) return '';
+    var s = Math.round(seconds);
+    var mm = String(Math.floor(s / 60) % 60);
+    var ss = String(s % 60).padStart(2, '0');
+    if (s >= 3600) return Math.floor(s / 3600) + ':' + mm.padStart(2, '0') + ':' + ss;
+    return mm + ':' + ss;
+  }
+
+  function formatResolution(f) {
+    return f.width && f.height ? f.width + '\u00d7' + f.height : '';
+  }
 
   function updateTabCounts(b) {
     if (!b) return;
@@ -42,6 +69,7 @@
   }
 
   function renderFileTable(files) {
+    lastFiles = files;
     if (!files || files.length === 0) {
       var msg = activeTab === 'quest'
         ? 'No files found on Quest.'
@@ -63,15 +91,27 @@
     Object.keys(groups).forEach(function (type) {
       groups[type].forEach(function (f) { allFiles.push(f); });
     });
-    allFiles.sort(function (a, b) { return (b.mtime || 0) - (a.mtime || 0); });
+    var value = sortValues[sortKey];
+    allFiles.sort(function (a, b) {
+      var va = value(a), vb = value(b);
+      var c = va < vb ? -1 : va > vb ? 1 : 0;
+      return sortDesc ? -c : c;
+    });
 
     var showLocate = activeTab === 'local';
-    var html = '<table class="file-table"><thead><tr><th>Name</th><th>Date</th><th>Size</th><th>Status</th><th></th></tr></thead><tbody>';
+    var html = '<table class="file-table"><thead><tr>';
+    [['name', 'Name'], ['date', 'Date'], ['length', 'Length'], ['resolution', 'Resolution'], ['size', 'Size']].forEach(function (col) {
+      var arrow = col[0] === sortKey ? (sortDesc ? ' \u25be' : ' \u25b4') : '';
+      html += '<th class="sortable" data-sort="' + col[0] + '">' + col[1] + arrow + '</th>';
+    });
+    html += '<th>Status</th><th></th></tr></thead><tbody>';
     allFiles.forEach(function (f) {
       var filePath = f.localPath || f.path;
       html += '<tr data-path="' + FQ.escapeHtml(filePath) + '">' +
         '<td>' + FQ.escapeHtml(f.fileName) + '</td>' +
-        '<td>' + FQ.formatDate(f.mtime) + '</td>' +
+        '<td>' + FQ.formatDate(fileDate(f)) + '</td>' +
+        '<td>' + formatLength(f.duration) + '</td>' +
+        '<td>' + formatResolution(f) + '</td>' +
         '<td>' + FQ.formatSize(f.size) + '</td>' +
         '<td>' + statusBadge(f) + '</td>' +
         '<td>' + (showLocate && filePath ? '<button class="btn-text btn-locate" data-locate="' + FQ.escapeHtml(filePath) + '">Show</button>' : '') + '</td>' +
@@ -81,6 +121,19 @@
 
     filesContent.innerHTML = html;
 
+    // Header click → sort, again to reverse
+    filesContent.querySelectorAll('.file-table th[data-sort]').forEach(function (th) {
+      th.addEventListener('click', function () {
+        if (sortKey === th.dataset.sort) {
+          sortDesc = !sortDesc;
+        } else {
+          sortKey = th.dataset.sort;
+          sortDesc = sortKey !== 'name';
+        }
+        renderFileTable(lastFiles);
+      });
+    });
+
     // Row click → select
     filesContent.querySelectorAll('.file-table tr[data-path]').forEach(function (row) {
       row.addEventListener('click', function () {
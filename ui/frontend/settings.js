@@ -73,6 +73,46 @@
     });
   }
 
+  // ── Auto-sync ──
+
+  var autoSyncCb = document.getElementById('auto-sync-cb');
+  if (autoSyncCb) {
+    autoSyncCb.addEventListener('change', function () {
+      var b = FQ.backend();
+      if (!b || !b.SetAutoSync) return;
+      b.SetAutoSync(autoSyncCb.checked).catch(function (err) {
+        autoSyncCb.checked = !autoSyncCb.checked;
+        FQ.setStatus(err.message || String(err), 'error');
+      });
+    });
+  }
+
+  // ── Wireless pairing ──
+
+  var pairBtn = document.getElementById('pair-btn');
+  if (pairBtn) {
+    pairBtn.addEventListener('click', function () {
+      var b = FQ.backend();
+      if (!b || !b.PairDevice) return;
+      var addr = document.getElementById('pair-addr-input').value.trim();
+      var code = document.getElementById('pair-code-input').value.trim();
+      var connect = document.getElementById('pair-connect-input').value.trim();
+      pairBtn.textContent = 'Pairing...';
+      pairBtn.disabled = true;
+      b.PairDevice(addr, code, connect).then(function (msg) {
+        pairBtn.textContent = 'Pair';
+        pairBtn.disabled = false;
+        document.getElementById('pair-code-input').value = '';
+        FQ.setStatus(msg, 'success');
+        loadDeviceSettings();
+      }).catch(function (err) {
+        pairBtn.textContent = 'Pair';
+        pairBtn.disabled = false;
+        FQ.setStatus(err.message || String(err), 'error');
+      });
+    });
+  }
+
   // ── Tool paths ──
 
   var saveToolPathsBtn = document.getElementById('save-tool-paths-btn');
@@ -106,6 +146,7 @@
       if (adbInput && cfg) adbInput.value = cfg.adbPath || '';
       var rcloneInput = document.getElementById('rclone-path-input');
       if (rcloneInput && cfg) rcloneInput.value = cfg.rclonePath || '';
+      if (autoSyncCb && cfg) autoSyncCb.checked = !!cfg.autoSync;
     });
   });
 })();
@@ -36,6 +36,8 @@
     deviceCard.innerHTML = devices.map(function (d) {
       var name = d.nickname || d.model || d.serial;
       var meta = d.serial;
+      if (d.total > 0) meta += ' · ' + FQ.formatSize(d.free) + ' free of ' + FQ.formatSize(d.total);
+      if (d.battery >= 0) meta += ' · ' + d.battery + '% battery' + (d.charging ? ' (charging)' : '');
       var stats = d.stats ? '<p class="device-stats">' + FQ.escapeHtml(d.stats) + '</p>' : '';
       return '<div class="device"><p class="device-name">' + FQ.escapeHtml(name) + '</p>' +
         '<p class="device-meta">' + FQ.escapeHtml(meta) + '</p>' + stats + '</div>';
@@ -80,10 +82,24 @@
     });
   }
 
+  // Headsets found on the LAN that aren't attached yet.
+  function renderDiscovered(found) {
+    var extra = (found || []).filter(function (d) { return !d.connected; });
+    if (extra.length === 0) return;
+    deviceCard.insertAdjacentHTML('beforeend', extra.map(function (d) {
+      var name = d.nickname || d.serial;
+      var kind = d.service === '_adb-tls-connect._tcp' ? 'wireless debugging' : 'wireless ADB';
+      return '<div class="device"><p class="device-name">' + FQ.escapeHtml(name) + '</p>' +
+        '<p class="device-meta">' + FQ.escapeHtml(d.addr) + ' · on network (' + kind + ')</p></div>';
+    }).join(''));
+  }
+
   function loadAll() {
+    var b = FQ.backend();
     FQ.refreshDevices().then(function (devs) {
       renderDevices(devs);
-    });
+      if (b && b.DiscoverDevices) return b.DiscoverDevices().then(renderDiscovered);
+    }).catch(function () {});
     loadConfig();
   }
 
@@ -175,6 +191,11 @@
     }
 
     cancelled = false;
+    beginSyncUI();
+    b.Sync(skipLocalCb.checked).then(onSyncDone).catch(onSyncFailed);
+  }
+
+  function beginSyncUI() {
     setSyncBtnState(true);
     previewBtn.disabled = true;
     syncSummary.hidden = true;
@@ -187,41 +208,61 @@
     progressFile.textContent = '';
 
     window.runtime.EventsOn('sync:progress', onSyncProgress);
+  }
 
-    b.Sync(skipLocalCb.checked)
-      .then(function (msg) {
-        if (cancelled) return;
-        window.runtime.EventsOff('sync:progress');
-        stopDots();
-        setSyncBtnState(false);
-        syncProgress.hidden = true;
-        syncResults.hidden = false;
-        syncResultsContent.innerHTML = '<p class="text-success" style="font-weight:600">Quest Complete!</p>' +
-          '<p class="text-muted text-sm">' + FQ.escapeHtml(msg) + '</p>' +
-          '<div style="margin-top:10px;display:flex;gap:8px">' +
-          '<button class="btn-text" id="sync-open-folder">View Loot</button>' +
-          '<button class="btn-text" id="sync-again">Another Quest!</button></div>';
-        var openBtn = document.getElementById('sync-open-folder');
-        if (openBtn) openBtn.addEventListener('click', function () {
-          if (b.OpenSyncFolder) b.OpenSyncFolder();
-        });
-        var againBtn = document.getElementById('sync-again');
-        if (againBtn) againBtn.addEventListener('click', function () {
-          syncResults.hidden = true;
-          setActions(false);
-        });
-        FQ.setStatus('', '');
-        loadAll();
-      })
-      .catch(function (err) {
-        if (cancelled) return;
-        window.runtime.EventsOff('sync:progress');
-        stopDots();
-        setSyncBtnState(false);
-        syncProgress.hidden = true;
-        FQ.setStatus(err.message || String(err), 'error');
-        setActions(false);
-      });
+  function onSyncDone(msg) {
+    var b = FQ.backend();
+    if (cancelled) return;
+    window.runtime.EventsOff('sync:progress');
+    stopDots();
+    setSyncBtnState(false);
+    syncProgress.hidden = true;
+    syncResults.hidden = false;
+    syncResultsContent.innerHTML = '<p class="text-success" style="font-weight:600">Quest Complete!</p>' +
+      '<p class="text-muted text-sm">' + FQ.escapeHtml(msg) + '</p>' +
+      '<div style="margin-top:10px;display:flex;gap:8px">' +
+      '<button class="btn-text" id="sync-open-folder">View Loot</button>' +
+      '<button class="btn-text" id="sync-again">Another Quest!</button></div>';
+    var openBtn = document.getElementById('sync-open-folder');
+    if (openBtn) openBtn.addEventListener('click', function () {
+      if (b && b.OpenSyncFolder) b.OpenSyncFolder();
+    });
+    var againBtn = document.getElementById('sync-again');
+    if (againBtn) againBtn.addEventListener('click', function () {
+      syncResults.hidden = true;
+      setActions(false);
+    });
+    FQ.setStatus('', '');
+    loadAll();
+  }
+
+  function onSyncFailed(err) {
+    if (cancelled) return;
+    window.runtime.EventsOff('sync:progress');
+    stopDots();
+    setSyncBtnState(false);
+    syncProgress.hidden = true;
+    FQ.setStatus(err.message || String(err), 'error');
+    setActions(false);
+  }
+
+  // ── Auto-sync on connect (started by the backend watcher) ──
+
+  if (window.runtime) {
+    window.runtime.EventsOn('devices:changed', function () {
+      if (!syncing) loadAll();
+    });
+    window.runtime.EventsOn('autosync:start', function () {
+      if (syncing) return;
+      cancelled = false;
+      beginSyncUI();
+      phaseLabel.textContent = 'Quest connected, syncing...';
+    });
+    window.runtime.EventsOn('autosync:done', function (res) {
+      if (!syncing) return;
+      if (res && res.error) onSyncFailed(new Error(res.error));
+      else onSyncDone((res && res.message) || '');
+    });
   }
 
   // ── Preview ──
@@ -298,6 +339,8 @@
         cleanModalBody.innerHTML =
           '<p><strong>' + info.eligible + '</strong> file' + (info.eligible !== 1 ? 's' : '') +
           ' (' + FQ.formatSize(info.totalSize) + ') fully backed up and safe to delete.</p>' +
+          '<p class="text-muted">' + (info.verified === info.eligible ? 'All' : info.verified + ' of ' + info.eligible) +
+          ' hash-verified on every destination.</p>' +
           (info.unsynced > 0 ? '<p class="text-muted">' + info.unsynced + ' file' + (info.unsynced !== 1 ? 's' : '') + ' on Quest are not yet fully synced and will be kept.</p>' : '') +
           '<p style="color:#d47a7a;margin-top:8px">This cannot be undone.</p>';
         cleanModal.hidden = false;